@@ -0,0 +1,450 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const (
+	anthropicBaseURL = "https://api.anthropic.com"
+	anthropicVersion = "2023-06-01"
+	anthropicModel   = "claude-sonnet-4-5"
+	// anthropicMaxTokens is used when no max_tokens is set, since the API
+	// requires one.
+	anthropicMaxTokens = 8192
+	// anthropicContextLength is the context window of current Claude models,
+	// which the models listing does not report.
+	anthropicContextLength = 200000
+	anthropicAPIKeyEnv     = "ANTHROPIC_API_KEY"
+	anthropicBaseURLEnv    = "ANTHROPIC_BASE_URL"
+)
+
+// thinkingModels are the ID prefixes of the Claude models with extended
+// thinking.
+var thinkingModels = []string{"claude-3-7-sonnet", "claude-sonnet-4", "claude-opus-4", "claude-haiku-4"}
+
+// thinkingBudgets maps each reasoning effort to the tokens a reply may spend
+// thinking. The API's minimum budget is 1024.
+var thinkingBudgets = map[string]int{
+	"minimal": 1024,
+	"low":     4096,
+	"medium":  8192,
+	"high":    16384,
+}
+
+func init() {
+	Register(Registration{
+		ID:     "anthropic",
+		KeyEnv: anthropicAPIKeyEnv,
+		New: func(apiKey string) Provider {
+			baseURL := os.Getenv(anthropicBaseURLEnv)
+			if baseURL == "" {
+				baseURL = anthropicBaseURL
+			}
+			return NewAnthropicProvider(apiKey, baseURL)
+		},
+	})
+}
+
+// anthropicProvider talks to the Anthropic Messages API.
+type anthropicProvider struct {
+	config endpoint
+}
+
+// NewAnthropicProvider creates a provider for the Anthropic Messages API
+// served at baseURL, such as a local stub server.
+func NewAnthropicProvider(apiKey, baseURL string) Provider {
+	return &anthropicProvider{config: endpoint{
+		Name:    "Anthropic",
+		APIKey:  apiKey,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Model:   anthropicModel,
+	}}
+}
+
+func (p *anthropicProvider) Name() string {
+	return p.config.Name
+}
+
+func (p *anthropicProvider) DefaultModel() string {
+	return p.config.Model
+}
+
+func (p *anthropicProvider) Capabilities() Capabilities {
+	return Capabilities{
+		Streaming:    true,
+		SystemPrompt: true,
+		Vision:       true,
+		Tools:        true,
+		ListModels:   true,
+	}
+}
+
+// anthropicBlock is a content block of a Messages API turn.
+type anthropicBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+	// ID, Name and Input describe a tool_use block
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// ToolUseID and Content describe a tool_result block
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	// Thinking and Signature describe a thinking block
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	// Source holds the data of an image block
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+// anthropicImageSource is a base64-encoded image.
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// anthropicMessage is a turn in the Messages API format.
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicMessages converts the history to the Messages API format. System
+// turns move to the separate system prompt, tool results become tool_result
+// blocks of a user turn, and consecutive turns from the same role are merged
+// because the API requires alternating roles. With thinking, signed
+// reasoning goes back as a thinking block ahead of the turn's other blocks.
+func anthropicMessages(history []ChatMessage, thinking bool) (string, []anthropicMessage) {
+	var system []string
+	var messages []anthropicMessage
+
+	for _, msg := range history {
+		if msg.Role == RoleSystem {
+			system = append(system, msg.Content)
+			continue
+		}
+
+		role := msg.Role
+		var blocks []anthropicBlock
+		if thinking && msg.ReasoningSignature != "" {
+			blocks = append(blocks, anthropicBlock{Type: "thinking", Thinking: msg.Reasoning, Signature: msg.ReasoningSignature})
+		}
+		if msg.Role == RoleTool {
+			role = RoleUser
+			blocks = append(blocks, anthropicBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content})
+		} else if msg.Content != "" {
+			blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+		}
+		for _, part := range msg.Parts {
+			if part.Type == PartImage {
+				blocks = append(blocks, anthropicBlock{Type: "image", Source: &anthropicImageSource{
+					Type:      "base64",
+					MediaType: part.MediaType,
+					Data:      base64.StdEncoding.EncodeToString(part.Data),
+				}})
+			} else {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+			}
+		}
+		for _, call := range msg.ToolCalls {
+			input := json.RawMessage(call.Arguments)
+			if !json.Valid(input) {
+				input = json.RawMessage("{}")
+			}
+			blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, blocks...)
+			continue
+		}
+		messages = append(messages, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	return strings.Join(system, "\n\n"), messages
+}
+
+// anthropicThinks reports whether model has extended thinking.
+func anthropicThinks(model string) bool {
+	for _, prefix := range thinkingModels {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// thinkingBudget returns the thinking budget of req, or 0 to leave thinking
+// off. While tools are in use, the API requires the last assistant turn to
+// start with its signed thinking, so thinking stays off when that turn has
+// none, such as when it was sent by another model.
+func thinkingBudget(req ChatRequest) int {
+	budget := thinkingBudgets[req.Params.ReasoningEffort]
+	if budget == 0 || !anthropicThinks(req.Model) {
+		return 0
+	}
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		msg := req.Messages[i]
+		if msg.Role == RoleUser {
+			break
+		}
+		if msg.Role == RoleAssistant && len(msg.ToolCalls) > 0 && msg.ReasoningSignature == "" {
+			return 0
+		}
+	}
+	return budget
+}
+
+func anthropicTools(tools []ToolDefinition) []map[string]interface{} {
+	defs := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		defs = append(defs, map[string]interface{}{
+			"name":         tool.Name,
+			"description":  tool.Description,
+			"input_schema": tool.Parameters,
+		})
+	}
+	return defs
+}
+
+func (p *anthropicProvider) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, p.config.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.config.APIKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	return req, nil
+}
+
+func (p *anthropicProvider) StreamChat(ctx context.Context, chatReq ChatRequest) <-chan StreamEvent {
+	return newStream(ctx, func(emit emitFunc) error {
+		budget := thinkingBudget(chatReq)
+		system, messages := anthropicMessages(chatReq.Messages, budget > 0)
+
+		// max_tokens includes the thinking, so the budget is added to the
+		// limit of the reply
+		maxTokens := chatReq.Params.MaxTokens
+		if maxTokens <= 0 {
+			maxTokens = anthropicMaxTokens
+		}
+		maxTokens += budget
+
+		requestBody := map[string]interface{}{
+			"model":      chatReq.Model,
+			"messages":   messages,
+			"max_tokens": maxTokens,
+			"stream":     true,
+		}
+		if system != "" {
+			requestBody["system"] = system
+		}
+		// The seed is left out; see unsupportedParams. Thinking requires the
+		// default temperature and top_p.
+		params := chatReq.Params
+		if budget > 0 {
+			requestBody["thinking"] = map[string]interface{}{"type": "enabled", "budget_tokens": budget}
+		} else {
+			if params.Temperature != nil {
+				requestBody["temperature"] = *params.Temperature
+			}
+			if params.TopP != nil {
+				requestBody["top_p"] = *params.TopP
+			}
+		}
+		if len(params.Stop) > 0 {
+			requestBody["stop_sequences"] = params.Stop
+		}
+		if len(chatReq.Tools) > 0 {
+			requestBody["tools"] = anthropicTools(chatReq.Tools)
+		}
+
+		jsonBody, err := json.Marshal(requestBody)
+		if err != nil {
+			return fmt.Errorf("Failed to encode Anthropic request: %w", err)
+		}
+
+		newRequest := func() (*http.Request, error) {
+			req, err := p.newRequest(ctx, "POST", "/v1/messages", bytes.NewReader(jsonBody))
+			if err != nil {
+				return nil, fmt.Errorf("Failed to create Anthropic request: %w", err)
+			}
+			return req, nil
+		}
+
+		client := streamClient(defaultStreamTimeout)
+		resp, err := sendWithRetry(ctx, client, p.config.Name, newRequest, emit)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return readAnthropicStream(p.config.Name, resp.Body, emit)
+	})
+}
+
+func (p *anthropicProvider) ListModels() ([]ModelInfo, error) {
+	req, err := p.newRequest(context.Background(), "GET", "/v1/models", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: defaultListTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseErrorResponse(p.config.Name, resp)
+	}
+
+	var listing struct {
+		Data []struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"display_name"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(listing.Data))
+	for _, model := range listing.Data {
+		info := ModelInfo{ID: model.ID, Name: model.DisplayName, ContextLength: anthropicContextLength, Vision: true, Tools: true, Reasoning: anthropicThinks(model.ID)}
+		if price, ok := LookupPrice(model.ID); ok {
+			info.Pricing = &price
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// anthropicUsage is the token accounting in message_start and
+// message_delta events. Input tokens exclude those read from or written to
+// the prompt cache.
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+// anthropicEvent covers the fields used from the Messages API stream events.
+type anthropicEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Usage anthropicUsage `json:"usage"`
+	Index int            `json:"index"`
+	// ContentBlock starts a text or tool_use block
+	ContentBlock struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"content_block"`
+	Delta struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// readAnthropicStream parses the server-sent events of a Messages API stream.
+// tool_use blocks are collected and sent as one EventToolCalls at the end.
+// An error event, an undecodable chunk or a stream that ends before
+// message_stop ends the stream with a ProviderError.
+func readAnthropicStream(provider string, body io.Reader, emit emitFunc) error {
+	var usage Usage
+	var calls []ToolCall
+	// toolBlocks maps content block indexes to their entry in calls
+	toolBlocks := make(map[int]int)
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		var event anthropicEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return malformedChunk(provider, data, err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			input := event.Message.Usage
+			usage.PromptTokens = input.InputTokens + input.CacheReadInputTokens + input.CacheCreationInputTokens
+			usage.CachedTokens = input.CacheReadInputTokens
+			usage.CompletionTokens = input.OutputTokens
+		case "message_delta":
+			// output_tokens in message_delta is cumulative
+			usage.CompletionTokens = event.Usage.OutputTokens
+			if event.Delta.StopReason == "max_tokens" && !emit(StreamEvent{Type: EventTruncated}) {
+				return nil
+			}
+		case "content_block_start":
+			if event.ContentBlock.Type == "tool_use" {
+				toolBlocks[event.Index] = len(calls)
+				calls = append(calls, ToolCall{ID: event.ContentBlock.ID, Name: event.ContentBlock.Name})
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "text_delta":
+				if !delta(emit, event.Delta.Text) {
+					return nil
+				}
+			case "thinking_delta":
+				if !reasoning(emit, event.Delta.Thinking) {
+					return nil
+				}
+			case "signature_delta":
+				if !emit(StreamEvent{Type: EventReasoning, Signature: event.Delta.Signature}) {
+					return nil
+				}
+			case "input_json_delta":
+				if i, ok := toolBlocks[event.Index]; ok {
+					calls[i].Arguments += event.Delta.PartialJSON
+				}
+			}
+		case "message_stop":
+			if len(calls) > 0 && !emit(StreamEvent{Type: EventToolCalls, ToolCalls: calls}) {
+				return nil
+			}
+			emit(StreamEvent{Type: EventUsage, Usage: &usage})
+			return nil
+		case "error":
+			return streamError(provider, event.Error.Type, event.Error.Message)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return networkError(provider, err)
+	}
+	return incompleteStream(provider)
+}
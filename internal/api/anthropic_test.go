@@ -0,0 +1,341 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// anthropicServer serves handler as the Messages API and returns a provider
+// pointed at it.
+func anthropicServer(t *testing.T, handler http.HandlerFunc) Provider {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return NewAnthropicProvider("test-key", server.URL)
+}
+
+// writeEvents writes events as a server-sent event stream.
+func writeEvents(w http.ResponseWriter, events ...string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, event := range events {
+		var typed struct {
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(event), &typed)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+	}
+}
+
+// collect drains a stream.
+func collect(events <-chan StreamEvent) []StreamEvent {
+	var all []StreamEvent
+	for event := range events {
+		all = append(all, event)
+	}
+	return all
+}
+
+func TestAnthropicStreamsDeltas(t *testing.T) {
+	provider := anthropicServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %q, want /v1/messages", r.URL.Path)
+		}
+		if key := r.Header.Get("x-api-key"); key != "test-key" {
+			t.Errorf("x-api-key = %q, want test-key", key)
+		}
+		var body struct {
+			Model     string `json:"model"`
+			System    string `json:"system"`
+			MaxTokens int    `json:"max_tokens"`
+			Stream    bool   `json:"stream"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if body.Model != "claude-test" || body.System != "Be brief." || body.MaxTokens != anthropicMaxTokens || !body.Stream {
+			t.Errorf("request = %+v", body)
+		}
+
+		writeEvents(w,
+			`{"type":"message_start","message":{"usage":{"input_tokens":10,"cache_read_input_tokens":5,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":", world"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}`,
+			`{"type":"message_stop"}`,
+		)
+	})
+
+	events := collect(provider.StreamChat(context.Background(), ChatRequest{
+		Model: "claude-test",
+		Messages: []ChatMessage{
+			{Role: RoleSystem, Content: "Be brief."},
+			{Role: RoleUser, Content: "Hi"},
+		},
+	}))
+
+	var text strings.Builder
+	var usage *Usage
+	for _, event := range events {
+		switch event.Type {
+		case EventDelta:
+			text.WriteString(event.Text)
+		case EventUsage:
+			usage = event.Usage
+		case EventTruncated, EventError:
+			t.Errorf("unexpected event %+v", event)
+		}
+	}
+	if text.String() != "Hello, world" {
+		t.Errorf("text = %q, want %q", text.String(), "Hello, world")
+	}
+	if usage == nil || usage.PromptTokens != 15 || usage.CachedTokens != 5 || usage.CompletionTokens != 4 {
+		t.Errorf("usage = %+v, want 15 prompt, 5 cached and 4 completion tokens", usage)
+	}
+	if last := events[len(events)-1]; last.Type != EventDone {
+		t.Errorf("last event = %v, want EventDone", last.Type)
+	}
+}
+
+func TestAnthropicReportsMaxTokens(t *testing.T) {
+	provider := anthropicServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			MaxTokens int `json:"max_tokens"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.MaxTokens != 3 {
+			t.Errorf("max_tokens = %d, want 3", body.MaxTokens)
+		}
+
+		writeEvents(w,
+			`{"type":"message_start","message":{"usage":{"input_tokens":8,"output_tokens":1}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"One two"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":3}}`,
+			`{"type":"message_stop"}`,
+		)
+	})
+
+	events := collect(provider.StreamChat(context.Background(), ChatRequest{
+		Model:    "claude-test",
+		Messages: []ChatMessage{{Role: RoleUser, Content: "Count"}},
+		Params:   Params{MaxTokens: 3},
+	}))
+
+	truncated := false
+	for _, event := range events {
+		if event.Type == EventTruncated {
+			truncated = true
+		}
+	}
+	if !truncated {
+		t.Errorf("no EventTruncated in %+v", events)
+	}
+	if last := events[len(events)-1]; last.Type != EventDone {
+		t.Errorf("last event = %v, want EventDone", last.Type)
+	}
+}
+
+func TestAnthropicMapsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		kind   ErrorKind
+		typ    string
+	}{
+		{
+			name:   "invalid key",
+			status: http.StatusUnauthorized,
+			body:   `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`,
+			kind:   ErrorAuth,
+			typ:    "authentication_error",
+		},
+		{
+			name:   "unknown model",
+			status: http.StatusNotFound,
+			body:   `{"type":"error","error":{"type":"not_found_error","message":"model: claude-nope"}}`,
+			kind:   ErrorModelNotFound,
+			typ:    "not_found_error",
+		},
+		{
+			name:   "bad request",
+			status: http.StatusBadRequest,
+			body:   `{"type":"error","error":{"type":"invalid_request_error","message":"messages: field required"}}`,
+			kind:   ErrorBadRequest,
+			typ:    "invalid_request_error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := anthropicServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("request-id", "req_123")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			})
+
+			events := collect(provider.StreamChat(context.Background(), ChatRequest{
+				Model:    "claude-test",
+				Messages: []ChatMessage{{Role: RoleUser, Content: "Hi"}},
+			}))
+
+			last := events[len(events)-1]
+			var perr *ProviderError
+			if last.Type != EventError || !errors.As(last.Err, &perr) {
+				t.Fatalf("last event = %+v, want EventError with a ProviderError", last)
+			}
+			if perr.Kind != tt.kind || perr.StatusCode != tt.status || perr.Type != tt.typ || perr.RequestID != "req_123" {
+				t.Errorf("error = %+v, want kind %s, status %d and type %s", perr, tt.kind, tt.status, tt.typ)
+			}
+		})
+	}
+}
+
+func TestAnthropicMapsStreamErrors(t *testing.T) {
+	provider := anthropicServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeEvents(w,
+			`{"type":"message_start","message":{"usage":{"input_tokens":8}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Par"}}`,
+			`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+		)
+	})
+
+	events := collect(provider.StreamChat(context.Background(), ChatRequest{
+		Model:    "claude-test",
+		Messages: []ChatMessage{{Role: RoleUser, Content: "Hi"}},
+	}))
+
+	last := events[len(events)-1]
+	var perr *ProviderError
+	if last.Type != EventError || !errors.As(last.Err, &perr) {
+		t.Fatalf("last event = %+v, want EventError with a ProviderError", last)
+	}
+	if perr.Kind != ErrorServer || perr.Type != "overloaded_error" {
+		t.Errorf("error = %+v, want kind %s and type overloaded_error", perr, ErrorServer)
+	}
+}
+
+func TestAnthropicReportsIncompleteStreams(t *testing.T) {
+	provider := anthropicServer(t, func(w http.ResponseWriter, r *http.Request) {
+		writeEvents(w,
+			`{"type":"message_start","message":{"usage":{"input_tokens":8}}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Par"}}`,
+		)
+	})
+
+	events := collect(provider.StreamChat(context.Background(), ChatRequest{
+		Model:    "claude-test",
+		Messages: []ChatMessage{{Role: RoleUser, Content: "Hi"}},
+	}))
+
+	last := events[len(events)-1]
+	var perr *ProviderError
+	if last.Type != EventError || !errors.As(last.Err, &perr) {
+		t.Fatalf("last event = %+v, want EventError with a ProviderError", last)
+	}
+	if perr.Kind != ErrorStream {
+		t.Errorf("error = %+v, want kind %s", perr, ErrorStream)
+	}
+	for _, event := range events {
+		if event.Type == EventDone || event.Type == EventUsage {
+			t.Errorf("unexpected event %+v", event)
+		}
+	}
+}
+
+func TestAnthropicThinking(t *testing.T) {
+	temperature := 0.5
+	provider := anthropicServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			MaxTokens   int      `json:"max_tokens"`
+			Temperature *float64 `json:"temperature"`
+			Thinking    struct {
+				Type         string `json:"type"`
+				BudgetTokens int    `json:"budget_tokens"`
+			} `json:"thinking"`
+			Messages []anthropicMessage `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		if body.Thinking.Type != "enabled" || body.Thinking.BudgetTokens != 4096 {
+			t.Errorf("thinking = %+v, want enabled with 4096 tokens", body.Thinking)
+		}
+		if body.MaxTokens != anthropicMaxTokens+4096 || body.Temperature != nil {
+			t.Errorf("max_tokens = %d, temperature = %v", body.MaxTokens, body.Temperature)
+		}
+		if first := body.Messages[1].Content[0]; first.Type != "thinking" || first.Thinking != "Look it up" || first.Signature != "sig1" {
+			t.Errorf("assistant turn starts with %+v, want its signed thinking", first)
+		}
+
+		writeEvents(w,
+			`{"type":"message_start","message":{"usage":{"input_tokens":8}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"It is sunny"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig2"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"Sunny."}}`,
+			`{"type":"message_stop"}`,
+		)
+	})
+
+	events := collect(provider.StreamChat(context.Background(), ChatRequest{
+		Model: "claude-sonnet-4-5",
+		Messages: []ChatMessage{
+			{Role: RoleUser, Content: "Weather?"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "t1", Name: "weather", Arguments: "{}"}}, Reasoning: "Look it up", ReasoningSignature: "sig1"},
+			{Role: RoleTool, ToolCallID: "t1", Content: "sunny"},
+		},
+		Params: Params{ReasoningEffort: "low", Temperature: &temperature},
+	}))
+
+	var thinking, signature string
+	for _, event := range events {
+		if event.Type == EventReasoning {
+			thinking += event.Text
+			if event.Signature != "" {
+				signature = event.Signature
+			}
+		}
+	}
+	if thinking != "It is sunny" || signature != "sig2" {
+		t.Errorf("reasoning = %q signed %q, want %q signed sig2", thinking, signature, "It is sunny")
+	}
+}
+
+func TestThinkingBudget(t *testing.T) {
+	user := ChatMessage{Role: RoleUser, Content: "Hi"}
+	unsigned := ChatMessage{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "t1", Name: "x"}}}
+	signed := unsigned
+	signed.Reasoning, signed.ReasoningSignature = "Hmm", "sig"
+	result := ChatMessage{Role: RoleTool, ToolCallID: "t1", Content: "ok"}
+
+	tests := []struct {
+		name     string
+		model    string
+		effort   string
+		messages []ChatMessage
+		want     int
+	}{
+		{"no effort", "claude-sonnet-4-5", "", []ChatMessage{user}, 0},
+		{"minimal", "claude-sonnet-4-5", "minimal", []ChatMessage{user}, 1024},
+		{"high", "claude-opus-4-1", "high", []ChatMessage{user}, 16384},
+		{"model without thinking", "claude-3-5-haiku-latest", "high", []ChatMessage{user}, 0},
+		{"signed tool loop", "claude-sonnet-4-5", "low", []ChatMessage{user, signed, result}, 4096},
+		{"unsigned tool loop", "claude-sonnet-4-5", "low", []ChatMessage{user, unsigned, result}, 0},
+		{"unsigned earlier turn", "claude-sonnet-4-5", "low", []ChatMessage{user, unsigned, result, user}, 4096},
+	}
+	for _, tt := range tests {
+		req := ChatRequest{Model: tt.model, Messages: tt.messages, Params: Params{ReasoningEffort: tt.effort}}
+		if got := thinkingBudget(req); got != tt.want {
+			t.Errorf("%s: thinkingBudget = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"Chat2/internal/config"
+)
+
+// ModelCacheTTL is how long a provider's model listing is served from disk
+// before it is fetched again.
+const ModelCacheTTL = 24 * time.Hour
+
+// modelCache is the on-disk form of a provider's model listing.
+type modelCache struct {
+	FetchedAt time.Time   `json:"fetched_at"`
+	Models    []ModelInfo `json:"models"`
+}
+
+// LoadModels returns the models offered by the provider of target, served
+// from the disk cache while it is younger than ModelCacheTTL. refresh skips
+// the cache. Published prices are added to the price table so that cost
+// accounting covers every listed model.
+func LoadModels(target string, apiKeys map[string]string, refresh bool) ([]ModelInfo, error) {
+	id, _ := SplitTarget(target)
+	path := filepath.Join(config.CacheDir(), "models", id+".json")
+
+	cache, err := readModelCache(path)
+	if err != nil || refresh || time.Since(cache.FetchedAt) > ModelCacheTTL {
+		models, fetchErr := fetchModels(id, apiKeys)
+		if fetchErr != nil {
+			// A stale listing beats none when the provider is unreachable
+			if err == nil && len(cache.Models) > 0 {
+				return cache.Models, nil
+			}
+			return nil, fetchErr
+		}
+		cache = modelCache{FetchedAt: time.Now(), Models: models}
+		writeModelCache(path, cache)
+	}
+
+	for _, model := range cache.Models {
+		if model.Pricing != nil {
+			setListedPrice(model.ID, *model.Pricing)
+		}
+	}
+	return cache.Models, nil
+}
+
+// CachedModel returns the catalog entry of model from the provider's cached
+// listing, without fetching it.
+func CachedModel(id, model string) (ModelInfo, bool) {
+	cache, err := readModelCache(filepath.Join(config.CacheDir(), "models", id+".json"))
+	if err != nil {
+		return ModelInfo{}, false
+	}
+	for _, info := range cache.Models {
+		if info.ID == model {
+			return info, true
+		}
+	}
+	return ModelInfo{}, false
+}
+
+func fetchModels(id string, apiKeys map[string]string) ([]ModelInfo, error) {
+	provider, ok := GetProvider(id, apiKeys)
+	if !ok {
+		return nil, &ProviderError{Provider: id, Kind: ErrorBadRequest, Message: "unknown provider"}
+	}
+	return provider.ListModels()
+}
+
+func readModelCache(path string) (modelCache, error) {
+	var cache modelCache
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache, err
+	}
+	err = json.Unmarshal(data, &cache)
+	return cache, err
+}
+
+// writeModelCache stores the listing; failures only cost a refetch later.
+func writeModelCache(path string, cache modelCache) {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	os.Rename(tmp, path)
+}
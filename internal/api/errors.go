@@ -0,0 +1,230 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorKind classifies why a provider request failed.
+type ErrorKind string
+
+const (
+	ErrorAuth          ErrorKind = "auth"
+	ErrorQuota         ErrorKind = "quota"
+	ErrorRateLimit     ErrorKind = "rate_limit"
+	ErrorModelNotFound ErrorKind = "model_not_found"
+	ErrorBadRequest    ErrorKind = "bad_request"
+	ErrorServer        ErrorKind = "server"
+	ErrorNetwork       ErrorKind = "network"
+	ErrorStream        ErrorKind = "stream"
+)
+
+// maxErrorBody caps how much of an error response is read.
+const maxErrorBody = 64 << 10
+
+// ProviderError is a failed request to a provider, with the details needed
+// to tell auth failures, exhausted quota and bad model names apart.
+type ProviderError struct {
+	Provider   string
+	Kind       ErrorKind
+	StatusCode int
+	// Type is the provider's own error type or code, if it sent one.
+	Type       string
+	Message    string
+	RequestID  string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Provider)
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " returned status %d", e.StatusCode)
+	} else {
+		b.WriteString(" error")
+	}
+	if e.Type != "" {
+		fmt.Fprintf(&b, " (%s)", e.Type)
+	}
+	if e.Message != "" {
+		b.WriteString(": " + e.Message)
+	}
+	return b.String()
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// requestIDHeaders are the response headers providers use for request IDs.
+var requestIDHeaders = []string{"x-request-id", "request-id", "x-amzn-requestid", "cf-ray"}
+
+// parseErrorResponse builds a ProviderError from a non-200 response. It
+// understands the OpenAI, OpenRouter, Anthropic and Ollama error bodies and
+// falls back to the raw body text.
+func parseErrorResponse(provider string, resp *http.Response) *ProviderError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	perr := &ProviderError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header),
+	}
+	for _, header := range requestIDHeaders {
+		if id := resp.Header.Get(header); id != "" {
+			perr.RequestID = id
+			break
+		}
+	}
+
+	perr.Type, perr.Message = parseErrorBody(body)
+	if perr.Message == "" {
+		perr.Message = strings.TrimSpace(string(body))
+		if perr.Message == "" {
+			perr.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+	perr.Kind = classifyError(resp.StatusCode, perr.Type, perr.Message)
+	return perr
+}
+
+// parseErrorBody extracts the error type and message from a JSON error body.
+// The error field is an object for OpenAI-style and Anthropic APIs and a
+// plain string for Ollama.
+func parseErrorBody(body []byte) (string, string) {
+	var envelope struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || len(envelope.Error) == 0 {
+		return "", ""
+	}
+
+	var text string
+	if err := json.Unmarshal(envelope.Error, &text); err == nil {
+		return "", text
+	}
+
+	var detail struct {
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(envelope.Error, &detail); err != nil {
+		return "", ""
+	}
+
+	errorType := detail.Type
+	if code := strings.Trim(string(detail.Code), `"`); code != "" && code != "null" {
+		if errorType == "" || errorType == code {
+			errorType = code
+		} else {
+			errorType += "/" + code
+		}
+	}
+	return errorType, detail.Message
+}
+
+// streamError builds a ProviderError for an error reported inside a stream.
+// OpenRouter reports the would-be HTTP status as a numeric error code.
+func streamError(provider, errorType, message string) *ProviderError {
+	status := 0
+	if code, err := strconv.Atoi(errorType); err == nil {
+		status, errorType = code, ""
+	}
+	return &ProviderError{
+		Provider:   provider,
+		Kind:       classifyError(status, errorType, message),
+		StatusCode: status,
+		Type:       errorType,
+		Message:    message,
+	}
+}
+
+// networkError wraps a transport failure such as a refused connection.
+func networkError(provider string, err error) *ProviderError {
+	return &ProviderError{
+		Provider: provider,
+		Kind:     ErrorNetwork,
+		Message:  err.Error(),
+		Err:      err,
+	}
+}
+
+// incompleteStream reports a stream that ended before the provider marked
+// the reply complete, usually because the connection dropped.
+func incompleteStream(provider string) *ProviderError {
+	return &ProviderError{
+		Provider: provider,
+		Kind:     ErrorStream,
+		Message:  "the stream ended before the reply was complete",
+	}
+}
+
+// malformedChunk reports a stream chunk that could not be decoded.
+func malformedChunk(provider string, data string, err error) *ProviderError {
+	if len(data) > 120 {
+		data = data[:120] + "…"
+	}
+	return &ProviderError{
+		Provider: provider,
+		Kind:     ErrorStream,
+		Message:  fmt.Sprintf("malformed stream chunk %q: %v", data, err),
+		Err:      err,
+	}
+}
+
+func classifyError(status int, errorType, message string) ErrorKind {
+	text := strings.ToLower(errorType + " " + message)
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden ||
+		strings.Contains(text, "authentication") || strings.Contains(text, "invalid api key") ||
+		strings.Contains(text, "invalid_api_key") || strings.Contains(text, "permission"):
+		return ErrorAuth
+	case status == http.StatusPaymentRequired || strings.Contains(text, "quota") ||
+		strings.Contains(text, "credit") || strings.Contains(text, "insufficient") ||
+		strings.Contains(text, "billing"):
+		return ErrorQuota
+	case status == http.StatusTooManyRequests || strings.Contains(text, "rate_limit") ||
+		strings.Contains(text, "rate limit"):
+		return ErrorRateLimit
+	case strings.Contains(text, "model") && (status == http.StatusNotFound ||
+		strings.Contains(text, "not found") || strings.Contains(text, "not_found") ||
+		strings.Contains(text, "does not exist") || strings.Contains(text, "not a valid model") ||
+		strings.Contains(text, "invalid model")):
+		return ErrorModelNotFound
+	case status >= 500 || strings.Contains(text, "overloaded") || strings.Contains(text, "server_error"):
+		return ErrorServer
+	case status >= 400:
+		return ErrorBadRequest
+	}
+	return ErrorServer
+}
+
+// parseRetryAfter reads Retry-After as seconds or an HTTP date, and the
+// millisecond variant some providers send.
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.Atoi(header.Get("retry-after-ms")); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
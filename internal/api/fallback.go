@@ -0,0 +1,53 @@
+package api
+
+import "errors"
+
+// fallbacks are the "provider/model" targets tried, in order, when the
+// current target fails before replying.
+var fallbacks []string
+
+// SetFallbacks configures the ordered fallback targets used by SendToAI.
+func SetFallbacks(targets []string) {
+	fallbacks = append([]string(nil), targets...)
+}
+
+// fallbackChain returns target followed by the configured fallbacks that
+// differ from it, are registered and have an API key if they need one.
+func fallbackChain(target string, apiKeys map[string]string) []string {
+	chain := []string{target}
+	seen := map[string]bool{resolveTarget(target): true}
+
+	for _, fallback := range fallbacks {
+		id, _ := SplitTarget(fallback)
+		reg, ok := registration(id)
+		if !ok || (!reg.Local && reg.KeyEnv != "" && apiKeys[id] == "") {
+			continue
+		}
+		if resolved := resolveTarget(fallback); !seen[resolved] {
+			seen[resolved] = true
+			chain = append(chain, fallback)
+		}
+	}
+	return chain
+}
+
+// resolveTarget spells out the model of a bare provider target so that
+// "openrouter" and "openrouter/<default model>" count as the same target.
+func resolveTarget(target string) string {
+	id, _ := SplitTarget(target)
+	return id + "/" + DefaultModel(target)
+}
+
+// canFallBack reports whether err means the target itself is unusable right
+// now, so another target may still answer. Bad requests would fail anywhere.
+func canFallBack(err error) bool {
+	var perr *ProviderError
+	if !errors.As(err, &perr) {
+		return false
+	}
+	switch perr.Kind {
+	case ErrorAuth, ErrorQuota, ErrorRateLimit, ErrorModelNotFound, ErrorServer, ErrorNetwork:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// stubProvider replays events as its reply.
+type stubProvider struct {
+	model  string
+	events []StreamEvent
+}
+
+func (p stubProvider) Name() string               { return "Stub" }
+func (p stubProvider) DefaultModel() string       { return p.model }
+func (p stubProvider) Capabilities() Capabilities { return Capabilities{Streaming: true} }
+
+func (p stubProvider) ListModels() ([]ModelInfo, error) { return nil, nil }
+
+func (p stubProvider) StreamChat(ctx context.Context, req ChatRequest) <-chan StreamEvent {
+	events := make(chan StreamEvent, len(p.events))
+	for _, event := range p.events {
+		events <- event
+	}
+	close(events)
+	return events
+}
+
+// registerStubs registers a stub provider per registration, replying with
+// the events given for its ID, and restores the registry and fallbacks when
+// the test ends.
+func registerStubs(t *testing.T, regs []Registration, replies map[string][]StreamEvent) {
+	t.Helper()
+	savedRegistry, savedFallbacks := registry, fallbacks
+	registry = append([]Registration(nil), registry...)
+	t.Cleanup(func() { registry, fallbacks = savedRegistry, savedFallbacks })
+
+	for _, reg := range regs {
+		provider := stubProvider{model: "default", events: replies[reg.ID]}
+		reg.New = func(string) Provider { return provider }
+		Register(reg)
+	}
+}
+
+func TestFallbackChain(t *testing.T) {
+	registerStubs(t, []Registration{
+		{ID: "stub-keyed", KeyEnv: "STUB_KEY"},
+		{ID: "stub-free"},
+		{ID: "stub-local", KeyEnv: "STUB_LOCAL_KEY", Local: true},
+	}, nil)
+
+	tests := []struct {
+		name      string
+		target    string
+		fallbacks []string
+		keys      map[string]string
+		want      []string
+	}{
+		{
+			name:      "no fallbacks",
+			target:    "stub-free",
+			fallbacks: nil,
+			want:      []string{"stub-free"},
+		},
+		{
+			name:      "skips providers without a key",
+			target:    "stub-free",
+			fallbacks: []string{"stub-keyed", "stub-local/llama"},
+			want:      []string{"stub-free", "stub-local/llama"},
+		},
+		{
+			name:      "keeps providers with a key",
+			target:    "stub-free",
+			fallbacks: []string{"stub-keyed/fast"},
+			keys:      map[string]string{"stub-keyed": "secret"},
+			want:      []string{"stub-free", "stub-keyed/fast"},
+		},
+		{
+			name:      "skips unknown providers",
+			target:    "stub-free",
+			fallbacks: []string{"nope/model", "stub-local"},
+			want:      []string{"stub-free", "stub-local"},
+		},
+		{
+			name:      "skips the target and duplicates",
+			target:    "stub-free",
+			fallbacks: []string{"stub-free/default", "stub-local", "stub-local/default", "stub-free/other"},
+			want:      []string{"stub-free", "stub-local", "stub-free/other"},
+		},
+	}
+	for _, tt := range tests {
+		SetFallbacks(tt.fallbacks)
+		if got := fallbackChain(tt.target, tt.keys); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: fallbackChain = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCanFallBack(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&ProviderError{Kind: ErrorAuth}, true},
+		{&ProviderError{Kind: ErrorQuota}, true},
+		{&ProviderError{Kind: ErrorRateLimit}, true},
+		{&ProviderError{Kind: ErrorModelNotFound}, true},
+		{&ProviderError{Kind: ErrorServer}, true},
+		{&ProviderError{Kind: ErrorNetwork}, true},
+		{&ProviderError{Kind: ErrorBadRequest}, false},
+		{&ProviderError{Kind: ErrorStream}, false},
+		{errors.New("Unknown provider: nope"), false},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		if got := canFallBack(tt.err); got != tt.want {
+			t.Errorf("canFallBack(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSendToAIFallsBack(t *testing.T) {
+	serverError := &ProviderError{Kind: ErrorServer, Message: "overloaded"}
+	badRequest := &ProviderError{Kind: ErrorBadRequest, Message: "bad"}
+
+	tests := []struct {
+		name     string
+		first    []StreamEvent
+		want     []EventType
+		fellBack bool
+	}{
+		{
+			name:     "error before output",
+			first:    []StreamEvent{{Type: EventError, Err: serverError}},
+			want:     []EventType{EventFallback, EventDelta, EventDone},
+			fellBack: true,
+		},
+		{
+			name:  "error after partial output",
+			first: []StreamEvent{{Type: EventDelta, Text: "Par"}, {Type: EventError, Err: serverError}},
+			want:  []EventType{EventDelta, EventError},
+		},
+		{
+			name:  "error after reasoning",
+			first: []StreamEvent{{Type: EventReasoning, Text: "Hmm"}, {Type: EventError, Err: serverError}},
+			want:  []EventType{EventReasoning, EventError},
+		},
+		{
+			name:  "bad request",
+			first: []StreamEvent{{Type: EventError, Err: badRequest}},
+			want:  []EventType{EventError},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registerStubs(t, []Registration{{ID: "stub-first"}, {ID: "stub-second"}}, map[string][]StreamEvent{
+				"stub-first":  tt.first,
+				"stub-second": {{Type: EventDelta, Text: "Hello"}, {Type: EventDone}},
+			})
+			SetFallbacks([]string{"stub-second"})
+
+			events := collect(SendToAI(context.Background(), "stub-first", ChatRequest{}, nil))
+			var got []EventType
+			for _, event := range events {
+				got = append(got, event.Type)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("events = %v, want %v", got, tt.want)
+			}
+			if tt.fellBack && (events[0].Target != "stub-second" || !errors.Is(events[0].Err, serverError)) {
+				t.Errorf("fallback event = %+v, want stub-second after the server error", events[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	ollamaBaseURL     = "http://localhost:11434"
+	ollamaHostEnv     = "OLLAMA_HOST"
+	localOpenAIURL    = "http://localhost:8080/v1"
+	localOpenAIURLEnv = "LOCAL_OPENAI_BASE_URL"
+
+	// localStreamTimeout is generous because local models on modest
+	// hardware can take minutes to load before the first token.
+	localStreamTimeout = 10 * time.Minute
+	// localDiscoveryTimeout keeps startup fast when no local server runs.
+	localDiscoveryTimeout = 3 * time.Second
+)
+
+func init() {
+	Register(Registration{
+		ID:    "ollama",
+		Local: true,
+		New: func(string) Provider {
+			baseURL := os.Getenv(ollamaHostEnv)
+			if baseURL == "" {
+				baseURL = ollamaBaseURL
+			}
+			if !strings.Contains(baseURL, "://") {
+				baseURL = "http://" + baseURL
+			}
+			return NewOllamaProvider(baseURL)
+		},
+	})
+
+	// llama.cpp, LM Studio and similar servers expose the OpenAI API locally
+	Register(Registration{
+		ID:    "local",
+		Local: true,
+		New: func(string) Provider {
+			baseURL := os.Getenv(localOpenAIURLEnv)
+			if baseURL == "" {
+				baseURL = localOpenAIURL
+			}
+			return &openAIProvider{
+				config: endpoint{
+					Name:    "Local",
+					BaseURL: strings.TrimSuffix(baseURL, "/"),
+				},
+				timeout:     localStreamTimeout,
+				listTimeout: localDiscoveryTimeout,
+			}
+		},
+	})
+}
+
+// ollamaProvider talks to the native Ollama chat API.
+type ollamaProvider struct {
+	config endpoint
+}
+
+// NewOllamaProvider creates a provider for the Ollama server at baseURL.
+func NewOllamaProvider(baseURL string) Provider {
+	return &ollamaProvider{config: endpoint{
+		Name:    "Ollama",
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}}
+}
+
+func (p *ollamaProvider) Name() string {
+	return p.config.Name
+}
+
+// DefaultModel is empty because Ollama has no default; targets always pin
+// one of the discovered models.
+func (p *ollamaProvider) DefaultModel() string {
+	return p.config.Model
+}
+
+func (p *ollamaProvider) Capabilities() Capabilities {
+	return Capabilities{
+		Streaming:    true,
+		SystemPrompt: true,
+		ListModels:   true,
+		JSONSchema:   true,
+	}
+}
+
+func (p *ollamaProvider) StreamChat(ctx context.Context, chatReq ChatRequest) <-chan StreamEvent {
+	return newStream(ctx, func(emit emitFunc) error {
+		requestBody := map[string]interface{}{
+			"model":    chatReq.Model,
+			"messages": plainMessages(chatReq.Messages),
+			"stream":   true,
+		}
+		if chatReq.ResponseFormat != nil {
+			requestBody["format"] = chatReq.ResponseFormat.Schema
+		}
+		if options := ollamaOptions(chatReq.Params); len(options) > 0 {
+			requestBody["options"] = options
+		}
+
+		jsonBody, err := json.Marshal(requestBody)
+		if err != nil {
+			return fmt.Errorf("Failed to encode Ollama request: %w", err)
+		}
+
+		newRequest := func() (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/api/chat", bytes.NewReader(jsonBody))
+			if err != nil {
+				return nil, fmt.Errorf("Failed to create Ollama request: %w", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			return req, nil
+		}
+
+		client := streamClient(localStreamTimeout)
+		resp, err := sendWithRetry(ctx, client, p.config.Name, newRequest, emit)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return readOllamaStream(p.config.Name, resp.Body, emit)
+	})
+}
+
+// ollamaOptions maps the generation settings to Ollama's model options.
+// Reasoning effort has no equivalent.
+func ollamaOptions(params Params) map[string]interface{} {
+	options := make(map[string]interface{})
+	if params.Temperature != nil {
+		options["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		options["top_p"] = *params.TopP
+	}
+	if params.MaxTokens > 0 {
+		options["num_predict"] = params.MaxTokens
+	}
+	if len(params.Stop) > 0 {
+		options["stop"] = params.Stop
+	}
+	if params.Seed != nil {
+		options["seed"] = *params.Seed
+	}
+	return options
+}
+
+func (p *ollamaProvider) ListModels() ([]ModelInfo, error) {
+	client := &http.Client{Timeout: localDiscoveryTimeout}
+	resp, err := client.Get(p.config.BaseURL + "/api/tags")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseErrorResponse(p.config.Name, resp)
+	}
+
+	var tags struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(tags.Models))
+	for _, model := range tags.Models {
+		models = append(models, ModelInfo{ID: model.Name, Name: model.Name})
+	}
+	return models, nil
+}
+
+// readOllamaStream reads the newline-delimited JSON objects that Ollama
+// streams, one per chunk, until one reports done. A stream that ends before
+// that is reported as incomplete.
+func readOllamaStream(provider string, body io.Reader, emit emitFunc) error {
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		var chunk struct {
+			Message struct {
+				Content  string `json:"content"`
+				Thinking string `json:"thinking"`
+			} `json:"message"`
+			Done            bool   `json:"done"`
+			DoneReason      string `json:"done_reason"`
+			Error           string `json:"error"`
+			PromptEvalCount int    `json:"prompt_eval_count"`
+			EvalCount       int    `json:"eval_count"`
+		}
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+			return malformedChunk(provider, scanner.Text(), err)
+		}
+
+		if chunk.Error != "" {
+			return streamError(provider, "", chunk.Error)
+		}
+		if !reasoning(emit, chunk.Message.Thinking) || !delta(emit, chunk.Message.Content) {
+			return nil
+		}
+		if chunk.Done {
+			if chunk.DoneReason == "length" && !emit(StreamEvent{Type: EventTruncated}) {
+				return nil
+			}
+			usage := &Usage{PromptTokens: chunk.PromptEvalCount, CompletionTokens: chunk.EvalCount}
+			emit(StreamEvent{Type: EventUsage, Usage: usage})
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return networkError(provider, err)
+	}
+	return incompleteStream(provider)
+}
@@ -0,0 +1,480 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"Chat2/internal/config"
+)
+
+func init() {
+	Register(Registration{
+		ID:     "openrouter",
+		KeyEnv: "OPENROUTER_API_KEY",
+		New: func(apiKey string) Provider {
+			return &openAIProvider{
+				config: endpoint{
+					Name:    "OpenRouter",
+					APIKey:  apiKey,
+					BaseURL: "https://openrouter.ai/api/v1",
+					Model:   "gpt-3.5-turbo",
+				},
+				reasoningObject: true,
+			}
+		},
+	})
+}
+
+// openAIProvider talks to any server implementing the OpenAI chat
+// completions API.
+type openAIProvider struct {
+	config  endpoint
+	headers map[string]string
+	// vision is set for endpoints whose models accept images
+	vision bool
+	// noTools and noJSONSchema are set for endpoints that reject tool
+	// definitions or a response_format schema
+	noTools      bool
+	noJSONSchema bool
+	// reasoningObject sends the reasoning effort as {"reasoning": {"effort":
+	// …}}, the form OpenRouter expects
+	reasoningObject bool
+	// timeout bounds how long a streamed reply may stall; zero uses
+	// defaultStreamTimeout
+	timeout time.Duration
+	// listTimeout bounds a model listing; zero uses defaultListTimeout
+	listTimeout time.Duration
+}
+
+const (
+	// maxToolCalls bounds the tool call index a stream chunk may name, so
+	// that a bad server cannot make the reader allocate without limit
+	maxToolCalls = 128
+)
+
+// RegisterOpenAICompatible registers an OpenAI-compatible endpoint declared
+// in the config file. Its ID is the lowercased name with dashes for spaces;
+// a name that gives an empty ID, contains a slash or clashes with a
+// registered provider is refused.
+func RegisterOpenAICompatible(provider config.ProviderConfig) error {
+	id := strings.ToLower(strings.Join(strings.Fields(provider.Name), "-"))
+	switch {
+	case id == "":
+		return fmt.Errorf("provider name %q is empty", provider.Name)
+	case strings.Contains(id, "/"):
+		return fmt.Errorf("provider name %q must not contain a slash", provider.Name)
+	}
+	for _, reg := range registry {
+		if reg.ID == id {
+			return fmt.Errorf("provider name %q clashes with the provider %s", provider.Name, id)
+		}
+	}
+
+	Register(Registration{
+		ID:     id,
+		KeyEnv: provider.APIKeyEnv,
+		New: func(apiKey string) Provider {
+			return &openAIProvider{
+				config: endpoint{
+					Name:    provider.Name,
+					APIKey:  apiKey,
+					BaseURL: strings.TrimSuffix(provider.BaseURL, "/"),
+					Model:   provider.Model,
+				},
+				headers:      provider.Headers,
+				vision:       provider.Vision,
+				noTools:      provider.Tools != nil && !*provider.Tools,
+				noJSONSchema: provider.JSONSchema != nil && !*provider.JSONSchema,
+			}
+		},
+	})
+	return nil
+}
+
+func (p *openAIProvider) setHeaders(req *http.Request) {
+	if p.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	}
+	for name, value := range p.headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+}
+
+func (p *openAIProvider) Name() string {
+	return p.config.Name
+}
+
+func (p *openAIProvider) DefaultModel() string {
+	return p.config.Model
+}
+
+func (p *openAIProvider) Capabilities() Capabilities {
+	return Capabilities{
+		Streaming:    true,
+		SystemPrompt: true,
+		Vision:       p.vision,
+		Tools:        !p.noTools,
+		ListModels:   true,
+		JSONSchema:   !p.noJSONSchema,
+	}
+}
+
+// openAIToolCall is a tool call in the chat completions format.
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// openAIMessage is a turn in the chat completions format. Content is a
+// string, or a list of openAIContentPart for turns with attachments.
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    interface{}      `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIContentPart is a text or image_url part of a multimodal turn.
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+func openAIMessages(history []ChatMessage) []openAIMessage {
+	messages := make([]openAIMessage, 0, len(history))
+	for _, msg := range history {
+		wire := openAIMessage{Role: msg.Role, Content: msg.Content, ToolCallID: msg.ToolCallID}
+		if len(msg.Parts) > 0 {
+			wire.Content = openAIContent(msg)
+		}
+		for _, call := range msg.ToolCalls {
+			toolCall := openAIToolCall{ID: call.ID, Type: "function"}
+			toolCall.Function.Name = call.Name
+			toolCall.Function.Arguments = call.Arguments
+			wire.ToolCalls = append(wire.ToolCalls, toolCall)
+		}
+		messages = append(messages, wire)
+	}
+	return messages
+}
+
+// openAIContent turns the content and attachments of msg into parts, with
+// images inlined as base64 data URLs.
+func openAIContent(msg ChatMessage) []openAIContentPart {
+	var parts []openAIContentPart
+	if msg.Content != "" {
+		parts = append(parts, openAIContentPart{Type: "text", Text: msg.Content})
+	}
+	for _, part := range msg.Parts {
+		if part.Type != PartImage {
+			parts = append(parts, openAIContentPart{Type: "text", Text: part.Text})
+			continue
+		}
+		url := "data:" + part.MediaType + ";base64," + base64.StdEncoding.EncodeToString(part.Data)
+		parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: url}})
+	}
+	return parts
+}
+
+func openAITools(tools []ToolDefinition) []map[string]interface{} {
+	defs := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		defs = append(defs, map[string]interface{}{
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":        tool.Name,
+				"description": tool.Description,
+				"parameters":  tool.Parameters,
+			},
+		})
+	}
+	return defs
+}
+
+func (p *openAIProvider) StreamChat(ctx context.Context, chatReq ChatRequest) <-chan StreamEvent {
+	return newStream(ctx, func(emit emitFunc) error {
+		requestBody := map[string]interface{}{
+			"model":    chatReq.Model,
+			"messages": openAIMessages(chatReq.Messages),
+			"stream":   true,
+			// Ask for a final chunk with the token usage of the reply
+			"stream_options": map[string]interface{}{"include_usage": true},
+		}
+		p.setParams(requestBody, chatReq.Params)
+		if len(chatReq.Tools) > 0 {
+			requestBody["tools"] = openAITools(chatReq.Tools)
+		}
+		if format := chatReq.ResponseFormat; format != nil {
+			requestBody["response_format"] = map[string]interface{}{
+				"type": "json_schema",
+				"json_schema": map[string]interface{}{
+					"name":   format.Name,
+					"schema": format.Schema,
+				},
+			}
+		}
+
+		jsonBody, err := json.Marshal(requestBody)
+		if err != nil {
+			return fmt.Errorf("Failed to encode %s request: %w", p.config.Name, err)
+		}
+
+		newRequest := func() (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/chat/completions", bytes.NewReader(jsonBody))
+			if err != nil {
+				return nil, fmt.Errorf("Failed to create %s request: %w", p.config.Name, err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			p.setHeaders(req)
+			return req, nil
+		}
+
+		client := streamClient(orDefault(p.timeout, defaultStreamTimeout))
+		resp, err := sendWithRetry(ctx, client, p.config.Name, newRequest, emit)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		return readOpenAIStream(p.config.Name, resp.Body, emit)
+	})
+}
+
+// setParams adds the generation settings that are set to body.
+func (p *openAIProvider) setParams(body map[string]interface{}, params Params) {
+	if params.Temperature != nil {
+		body["temperature"] = *params.Temperature
+	}
+	if params.TopP != nil {
+		body["top_p"] = *params.TopP
+	}
+	if params.MaxTokens > 0 {
+		body["max_tokens"] = params.MaxTokens
+	}
+	if len(params.Stop) > 0 {
+		body["stop"] = params.Stop
+	}
+	if params.Seed != nil {
+		body["seed"] = *params.Seed
+	}
+	if params.ReasoningEffort != "" {
+		if p.reasoningObject {
+			body["reasoning"] = map[string]interface{}{"effort": params.ReasoningEffort}
+		} else {
+			body["reasoning_effort"] = params.ReasoningEffort
+		}
+	}
+}
+
+func (p *openAIProvider) ListModels() ([]ModelInfo, error) {
+	req, err := http.NewRequest("GET", p.config.BaseURL+"/models", nil)
+	if err != nil {
+		return nil, err
+	}
+	p.setHeaders(req)
+
+	client := &http.Client{Timeout: orDefault(p.listTimeout, defaultListTimeout)}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseErrorResponse(p.config.Name, resp)
+	}
+
+	// OpenRouter extends the standard listing with names, context length,
+	// per-token prices and supported features
+	var listing struct {
+		Data []struct {
+			ID            string `json:"id"`
+			Name          string `json:"name"`
+			ContextLength int    `json:"context_length"`
+			Pricing       *struct {
+				Prompt         string `json:"prompt"`
+				Completion     string `json:"completion"`
+				InputCacheRead string `json:"input_cache_read"`
+			} `json:"pricing"`
+			Architecture struct {
+				InputModalities []string `json:"input_modalities"`
+			} `json:"architecture"`
+			SupportedParameters []string `json:"supported_parameters"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return nil, err
+	}
+
+	models := make([]ModelInfo, 0, len(listing.Data))
+	for _, model := range listing.Data {
+		info := ModelInfo{
+			ID:            model.ID,
+			Name:          model.Name,
+			ContextLength: model.ContextLength,
+			Vision:        slices.Contains(model.Architecture.InputModalities, "image"),
+			Tools:         slices.Contains(model.SupportedParameters, "tools"),
+			Reasoning:     slices.Contains(model.SupportedParameters, "reasoning"),
+			JSONSchema:    slices.Contains(model.SupportedParameters, "structured_outputs"),
+			Features:      len(model.SupportedParameters) > 0,
+		}
+		if model.Pricing != nil {
+			info.Pricing = &Price{
+				Input:       perMillion(model.Pricing.Prompt),
+				Output:      perMillion(model.Pricing.Completion),
+				CachedInput: perMillion(model.Pricing.InputCacheRead),
+			}
+		} else if price, ok := LookupPrice(model.ID); ok {
+			info.Pricing = &price
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// perMillion converts a per-token price string to dollars per million tokens.
+func perMillion(perToken string) float64 {
+	price, err := strconv.ParseFloat(perToken, 64)
+	if err != nil {
+		return 0
+	}
+	return price * 1e6
+}
+
+func orDefault(timeout, fallback time.Duration) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+	return fallback
+}
+
+// readOpenAIStream parses the server-sent events of an OpenAI-compatible
+// chat completion stream. Tool call fragments are collected by index and
+// sent as one EventToolCalls at the end. An error payload, an undecodable
+// chunk or a stream that ends before [DONE] or a finish reason ends the
+// stream with a ProviderError.
+func readOpenAIStream(provider string, body io.Reader, emit emitFunc) error {
+	var calls []ToolCall
+	complete := false
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data: ") {
+			data := strings.TrimPrefix(line, "data: ")
+			if data == "[DONE]" {
+				complete = true
+				break
+			}
+
+			var chunk struct {
+				Choices []struct {
+					FinishReason string `json:"finish_reason"`
+					Delta        struct {
+						Content string `json:"content"`
+						// OpenRouter sends reasoning, DeepSeek and vLLM
+						// reasoning_content
+						Reasoning        string `json:"reasoning"`
+						ReasoningContent string `json:"reasoning_content"`
+						ToolCalls        []struct {
+							Index    int    `json:"index"`
+							ID       string `json:"id"`
+							Function struct {
+								Name      string `json:"name"`
+								Arguments string `json:"arguments"`
+							} `json:"function"`
+						} `json:"tool_calls"`
+					} `json:"delta"`
+				} `json:"choices"`
+				Usage *struct {
+					PromptTokens        int `json:"prompt_tokens"`
+					CompletionTokens    int `json:"completion_tokens"`
+					PromptTokensDetails struct {
+						CachedTokens int `json:"cached_tokens"`
+					} `json:"prompt_tokens_details"`
+				} `json:"usage"`
+				Error json.RawMessage `json:"error"`
+			}
+
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				return malformedChunk(provider, data, err)
+			}
+			if len(chunk.Error) > 0 {
+				errorType, message := parseErrorBody([]byte(data))
+				return streamError(provider, errorType, message)
+			}
+			if len(chunk.Choices) > 0 {
+				choice := chunk.Choices[0].Delta
+				if chunk.Choices[0].FinishReason != "" {
+					complete = true
+				}
+				if !reasoning(emit, choice.Reasoning+choice.ReasoningContent) || !delta(emit, choice.Content) {
+					return nil
+				}
+				if chunk.Choices[0].FinishReason == "length" && !emit(StreamEvent{Type: EventTruncated}) {
+					return nil
+				}
+				for _, fragment := range choice.ToolCalls {
+					if fragment.Index < 0 || fragment.Index >= maxToolCalls {
+						return malformedChunk(provider, data, fmt.Errorf("tool call index %d out of range", fragment.Index))
+					}
+					for len(calls) <= fragment.Index {
+						calls = append(calls, ToolCall{})
+					}
+					call := &calls[fragment.Index]
+					if fragment.ID != "" {
+						call.ID = fragment.ID
+					}
+					call.Name += fragment.Function.Name
+					call.Arguments += fragment.Function.Arguments
+				}
+			}
+			if chunk.Usage != nil {
+				usage := &Usage{
+					PromptTokens:     chunk.Usage.PromptTokens,
+					CompletionTokens: chunk.Usage.CompletionTokens,
+					CachedTokens:     chunk.Usage.PromptTokensDetails.CachedTokens,
+				}
+				if !emit(StreamEvent{Type: EventUsage, Usage: usage}) {
+					return nil
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return networkError(provider, err)
+	}
+	if !complete {
+		return incompleteStream(provider)
+	}
+
+	if len(calls) > 0 {
+		// Some local servers leave out call IDs, which results must echo
+		for i := range calls {
+			if calls[i].ID == "" {
+				calls[i].ID = fmt.Sprintf("call_%d", i)
+			}
+		}
+		emit(StreamEvent{Type: EventToolCalls, ToolCalls: calls})
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// readOpenAI runs readOpenAIStream over body and returns the events it
+// emitted and its result.
+func readOpenAI(body string) ([]StreamEvent, error) {
+	var events []StreamEvent
+	err := readOpenAIStream("Test", strings.NewReader(body), func(event StreamEvent) bool {
+		events = append(events, event)
+		return true
+	})
+	return events, err
+}
+
+func TestReadOpenAIStreamDeltas(t *testing.T) {
+	events, err := readOpenAI(`data: {"choices":[{"delta":{"role":"assistant","content":""}}]}
+
+data: {"choices":[{"delta":{"reasoning_content":"Thinking"}}]}
+
+: keep-alive
+data: {"choices":[{"delta":{"content":"Hello"}}]}
+
+data: {"choices":[{"delta":{"content":", world"},"finish_reason":"stop"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3,"prompt_tokens_details":{"cached_tokens":4}}}
+
+data: [DONE]
+
+data: {"choices":[{"delta":{"content":"ignored"}}]}
+`)
+	if err != nil {
+		t.Fatalf("readOpenAIStream: %v", err)
+	}
+
+	var text, thinking strings.Builder
+	var usage *Usage
+	for _, event := range events {
+		switch event.Type {
+		case EventDelta:
+			text.WriteString(event.Text)
+		case EventReasoning:
+			thinking.WriteString(event.Text)
+		case EventUsage:
+			usage = event.Usage
+		default:
+			t.Errorf("unexpected event %+v", event)
+		}
+	}
+	if text.String() != "Hello, world" || thinking.String() != "Thinking" {
+		t.Errorf("text = %q, reasoning = %q", text.String(), thinking.String())
+	}
+	if usage == nil || *usage != (Usage{PromptTokens: 12, CompletionTokens: 3, CachedTokens: 4}) {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestReadOpenAIStreamToolCalls(t *testing.T) {
+	events, err := readOpenAI(`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_a","function":{"name":"read_","arguments":""}}]}}]}
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"name":"file","arguments":"{\"path\":"}}]}}]}
+data: {"choices":[{"delta":{"tool_calls":[{"index":1,"function":{"name":"list_dir","arguments":"{}"}}]}}]}
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"go.mod\"}"}}]}}]}
+data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}
+data: [DONE]
+`)
+	if err != nil {
+		t.Fatalf("readOpenAIStream: %v", err)
+	}
+	if len(events) != 1 || events[0].Type != EventToolCalls {
+		t.Fatalf("events = %+v, want one EventToolCalls", events)
+	}
+	want := []ToolCall{
+		{ID: "call_a", Name: "read_file", Arguments: `{"path":"go.mod"}`},
+		// A call without an ID gets one, since its result must name it
+		{ID: "call_1", Name: "list_dir", Arguments: "{}"},
+	}
+	calls := events[0].ToolCalls
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %+v, want %+v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d = %+v, want %+v", i, calls[i], want[i])
+		}
+	}
+}
+
+func TestReadOpenAIStreamTruncated(t *testing.T) {
+	events, err := readOpenAI(`data: {"choices":[{"delta":{"content":"One two"},"finish_reason":"length"}]}
+data: [DONE]
+`)
+	if err != nil {
+		t.Fatalf("readOpenAIStream: %v", err)
+	}
+	if len(events) != 2 || events[1].Type != EventTruncated {
+		t.Errorf("events = %+v, want a delta and EventTruncated", events)
+	}
+}
+
+func TestReadOpenAIStreamFinishReasonWithoutDone(t *testing.T) {
+	events, err := readOpenAI(`data: {"choices":[{"delta":{"content":"Hi"},"finish_reason":"stop"}]}
+`)
+	if err != nil {
+		t.Fatalf("readOpenAIStream: %v", err)
+	}
+	if len(events) != 1 || events[0].Text != "Hi" {
+		t.Errorf("events = %+v, want one delta", events)
+	}
+}
+
+func TestReadOpenAIStreamErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		kind ErrorKind
+		text string
+	}{
+		{
+			name: "malformed line",
+			body: "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\ndata: {not json\n",
+			kind: ErrorStream,
+			text: "malformed stream chunk",
+		},
+		{
+			name: "tool call index out of range",
+			body: `data: {"choices":[{"delta":{"tool_calls":[{"index":100000000,"function":{"name":"x"}}]}}]}` + "\n",
+			kind: ErrorStream,
+			text: "out of range",
+		},
+		{
+			name: "negative tool call index",
+			body: `data: {"choices":[{"delta":{"tool_calls":[{"index":-1,"function":{"name":"x"}}]}}]}` + "\n",
+			kind: ErrorStream,
+			text: "out of range",
+		},
+		{
+			name: "error chunk",
+			body: `data: {"error":{"code":429,"message":"Rate limit exceeded"}}` + "\n",
+			kind: ErrorRateLimit,
+			text: "Rate limit exceeded",
+		},
+		{
+			name: "ends before done",
+			body: `data: {"choices":[{"delta":{"content":"Hel"}}]}` + "\n",
+			kind: ErrorStream,
+			text: "ended before the reply was complete",
+		},
+		{
+			name: "empty body",
+			body: "",
+			kind: ErrorStream,
+			text: "ended before the reply was complete",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readOpenAI(tt.body)
+			var perr *ProviderError
+			if !errors.As(err, &perr) {
+				t.Fatalf("err = %v, want a ProviderError", err)
+			}
+			if perr.Kind != tt.kind || !strings.Contains(perr.Error(), tt.text) {
+				t.Errorf("err = %v (kind %s), want kind %s mentioning %q", perr, perr.Kind, tt.kind, tt.text)
+			}
+		})
+	}
+}
+
+func TestReadOpenAIStreamStopsWhenCancelled(t *testing.T) {
+	count := 0
+	err := readOpenAIStream("Test", strings.NewReader(`data: {"choices":[{"delta":{"content":"a"}}]}
+data: {"choices":[{"delta":{"content":"b"}}]}
+data: [DONE]
+`), func(StreamEvent) bool {
+		count++
+		return false
+	})
+	if err != nil || count != 1 {
+		t.Errorf("err = %v after %d events, want nil after 1", err, count)
+	}
+}
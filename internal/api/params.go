@@ -0,0 +1,168 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"Chat2/internal/config"
+)
+
+// Params are the generation settings of a request. Unset fields leave the
+// provider's default in place.
+type Params struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Seed        *int     `json:"seed,omitempty"`
+	// ReasoningEffort is "minimal", "low", "medium" or "high" for models
+	// that reason before answering.
+	ReasoningEffort string `json:"reasoning_effort,omitempty"`
+}
+
+// ParamsFromConfig converts generation settings read from the config file.
+func ParamsFromConfig(cfg config.GenerationParams) Params {
+	return Params{
+		Temperature:     cfg.Temperature,
+		TopP:            cfg.TopP,
+		MaxTokens:       cfg.MaxTokens,
+		Stop:            cfg.Stop,
+		Seed:            cfg.Seed,
+		ReasoningEffort: cfg.ReasoningEffort,
+	}
+}
+
+// ParamNames lists the settings Set accepts, in display order.
+var ParamNames = []string{"temperature", "top_p", "max_tokens", "stop", "seed", "reasoning_effort"}
+
+var reasoningEfforts = []string{"minimal", "low", "medium", "high"}
+
+// unsupportedParams lists, per provider ID, the settings its requests leave
+// out. Anthropic has no seed and Ollama no reasoning effort.
+var unsupportedParams = map[string][]string{
+	"anthropic": {"seed"},
+	"ollama":    {"reasoning_effort"},
+}
+
+// SupportsParam reports whether the provider with the given ID sends the
+// setting called name.
+func SupportsParam(id, name string) bool {
+	return !slices.Contains(unsupportedParams[id], name)
+}
+
+// Merge returns p with the fields that are set in over replacing its own.
+func (p Params) Merge(over Params) Params {
+	if over.Temperature != nil {
+		p.Temperature = over.Temperature
+	}
+	if over.TopP != nil {
+		p.TopP = over.TopP
+	}
+	if over.MaxTokens > 0 {
+		p.MaxTokens = over.MaxTokens
+	}
+	if over.Stop != nil {
+		p.Stop = over.Stop
+	}
+	if over.Seed != nil {
+		p.Seed = over.Seed
+	}
+	if over.ReasoningEffort != "" {
+		p.ReasoningEffort = over.ReasoningEffort
+	}
+	return p
+}
+
+// Set parses value into the setting called name. An empty value unsets it.
+// Stop sequences are separated by commas.
+func (p *Params) Set(name, value string) error {
+	value = strings.TrimSpace(value)
+	switch name {
+	case "temperature":
+		return setFloat(&p.Temperature, name, value, 0, 2)
+	case "top_p":
+		return setFloat(&p.TopP, name, value, 0, 1)
+	case "max_tokens":
+		if value == "" {
+			p.MaxTokens = 0
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("max_tokens must be a positive whole number")
+		}
+		p.MaxTokens = n
+	case "stop":
+		p.Stop = nil
+		for _, stop := range strings.Split(value, ",") {
+			if stop = strings.TrimSpace(stop); stop != "" {
+				p.Stop = append(p.Stop, stop)
+			}
+		}
+	case "seed":
+		if value == "" {
+			p.Seed = nil
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("seed must be a whole number")
+		}
+		p.Seed = &n
+	case "reasoning_effort":
+		value = strings.ToLower(value)
+		if value != "" && !slices.Contains(reasoningEfforts, value) {
+			return fmt.Errorf("reasoning_effort must be one of %s", strings.Join(reasoningEfforts, ", "))
+		}
+		p.ReasoningEffort = value
+	default:
+		return fmt.Errorf("unknown setting %q; use one of %s", name, strings.Join(ParamNames, ", "))
+	}
+	return nil
+}
+
+// Get formats the setting called name, or returns an empty string if it is
+// unset.
+func (p Params) Get(name string) string {
+	switch name {
+	case "temperature":
+		return formatFloat(p.Temperature)
+	case "top_p":
+		return formatFloat(p.TopP)
+	case "max_tokens":
+		if p.MaxTokens > 0 {
+			return strconv.Itoa(p.MaxTokens)
+		}
+	case "stop":
+		return strings.Join(p.Stop, ",")
+	case "seed":
+		if p.Seed != nil {
+			return strconv.Itoa(*p.Seed)
+		}
+	case "reasoning_effort":
+		return p.ReasoningEffort
+	}
+	return ""
+}
+
+func setFloat(field **float64, name, value string, low, high float64) error {
+	if value == "" {
+		*field = nil
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < low || f > high {
+		return fmt.Errorf("%s must be a number from %g to %g", name, low, high)
+	}
+	*field = &f
+	return nil
+}
+
+func formatFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'g', -1, 64)
+}
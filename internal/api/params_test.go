@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"Chat2/internal/config"
+)
+
+func TestParamsFromConfig(t *testing.T) {
+	temperature, topP, seed := 0.7, 0.9, 42
+	cfg := config.GenerationParams{
+		Temperature:     &temperature,
+		TopP:            &topP,
+		MaxTokens:       512,
+		Stop:            []string{"END"},
+		Seed:            &seed,
+		ReasoningEffort: "low",
+	}
+	// Both types share their JSON names, so a field left out of the
+	// conversion shows up as a difference
+	want, _ := json.Marshal(cfg)
+	got, _ := json.Marshal(ParamsFromConfig(cfg))
+	if string(got) != string(want) {
+		t.Errorf("ParamsFromConfig = %s, want %s", got, want)
+	}
+
+	price := config.Price{Input: 3, Output: 15, CachedInput: 0.3}
+	want, _ = json.Marshal(price)
+	got, _ = json.Marshal(PriceFromConfig(price))
+	if string(got) != string(want) {
+		t.Errorf("PriceFromConfig = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"Chat2/internal/config"
+)
+
+// Price is the cost of a model in US dollars per million tokens.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+	// CachedInput applies to prompt tokens served from the provider's
+	// cache. Zero means cached tokens cost the same as Input.
+	CachedInput float64 `json:"cached_input"`
+}
+
+// PriceFromConfig converts a price read from the config file.
+func PriceFromConfig(cfg config.Price) Price {
+	return Price{Input: cfg.Input, Output: cfg.Output, CachedInput: cfg.CachedInput}
+}
+
+// pricesMu guards prices, which model listings fill in the background
+// while replies are priced.
+var pricesMu sync.RWMutex
+
+// prices maps model IDs, or prefixes of dated model IDs, to their price.
+var prices = map[string]Price{
+	"gpt-3.5-turbo":     {Input: 0.50, Output: 1.50},
+	"gpt-4o":            {Input: 2.50, Output: 10.00, CachedInput: 1.25},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60, CachedInput: 0.075},
+	"gpt-4.1":           {Input: 2.00, Output: 8.00, CachedInput: 0.50},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60, CachedInput: 0.10},
+	"gpt-4.1-nano":      {Input: 0.10, Output: 0.40, CachedInput: 0.025},
+	"o4-mini":           {Input: 1.10, Output: 4.40, CachedInput: 0.275},
+	"claude-opus-4-1":   {Input: 15.00, Output: 75.00, CachedInput: 1.50},
+	"claude-sonnet-4-5": {Input: 3.00, Output: 15.00, CachedInput: 0.30},
+	"claude-sonnet-4":   {Input: 3.00, Output: 15.00, CachedInput: 0.30},
+	"claude-haiku-4-5":  {Input: 1.00, Output: 5.00, CachedInput: 0.10},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4.00, CachedInput: 0.08},
+}
+
+// SetPrice adds or overrides the price of a model.
+func SetPrice(model string, price Price) {
+	pricesMu.Lock()
+	defer pricesMu.Unlock()
+	prices[model] = price
+}
+
+// setListedPrice adds the price a model listing reports, unless the model
+// already has one.
+func setListedPrice(model string, price Price) {
+	pricesMu.Lock()
+	defer pricesMu.Unlock()
+	if _, known := prices[model]; !known {
+		prices[model] = price
+	}
+}
+
+// LookupPrice finds the price of model. Provider prefixes such as
+// "openai/" are ignored, and dated snapshots match their base model.
+func LookupPrice(model string) (Price, bool) {
+	pricesMu.RLock()
+	defer pricesMu.RUnlock()
+
+	if price, ok := prices[model]; ok {
+		return price, true
+	}
+
+	if _, name, found := strings.Cut(model, "/"); found {
+		if price, ok := prices[name]; ok {
+			return price, true
+		}
+		model = name
+	}
+
+	// Prefer the longest matching prefix so "gpt-4o-mini-2024" does not
+	// match "gpt-4o"
+	var candidates []string
+	for id := range prices {
+		if strings.HasPrefix(model, id+"-") {
+			candidates = append(candidates, id)
+		}
+	}
+	if len(candidates) == 0 {
+		return Price{}, false
+	}
+	sort.Slice(candidates, func(i, j int) bool { return len(candidates[i]) > len(candidates[j]) })
+	return prices[candidates[0]], true
+}
+
+// Cost returns the dollar cost of usage for the model of target. Local
+// providers are free. The second result is false when the price is unknown.
+func Cost(target string, usage Usage) (float64, bool) {
+	id, _ := SplitTarget(target)
+	for _, reg := range registry {
+		if reg.ID == id && reg.Local {
+			return 0, true
+		}
+	}
+
+	price, ok := LookupPrice(DefaultModel(target))
+	if !ok {
+		return 0, false
+	}
+
+	cachedPrice := price.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = price.Input
+	}
+
+	uncached := usage.PromptTokens - usage.CachedTokens
+	cost := float64(uncached)*price.Input +
+		float64(usage.CachedTokens)*cachedPrice +
+		float64(usage.CompletionTokens)*price.Output
+	return cost / 1e6, true
+}
@@ -1,115 +1,413 @@
 package api
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
-	"time"
+)
 
-	"Chat2/internal/types"
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
 
-	tea "github.com/charmbracelet/bubbletea"
+// ChatMessage is a single turn of the conversation sent to a provider.
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// ToolCalls are the tools an assistant turn asked to run.
+	ToolCalls []ToolCall `json:"-"`
+	// ToolCallID links a tool turn holding a result to its call.
+	ToolCallID string `json:"-"`
+	// Parts are attachments sent after Content in a user turn.
+	Parts []ContentPart `json:"-"`
+	// Reasoning and ReasoningSignature are the signed thinking of an
+	// assistant turn, which Anthropic needs back while tools are in use.
+	Reasoning          string `json:"-"`
+	ReasoningSignature string `json:"-"`
+}
+
+// Kinds of ContentPart
+const (
+	PartText  = "text"
+	PartImage = "image"
 )
 
-var Providers = map[string]types.AIProvider{
-	"openrouter": {
-		Name:    "OpenRouter",
-		BaseURL: "https://openrouter.ai/api/v1/chat/completions",
-		Model:   "gpt-3.5-turbo",
-	},
+// ContentPart is an attachment of a turn: inlined text, or an image for
+// vision models.
+type ContentPart struct {
+	Type string
+	Text string
+	// MediaType and Data hold the raw bytes of an image part.
+	MediaType string
+	Data      []byte
+}
+
+// ToolCall is a model's request to run a local tool. Arguments holds a JSON
+// object.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// ToolDefinition describes a tool the model may call. Parameters is the JSON
+// schema of the arguments object.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Parameters  json.RawMessage
+}
+
+// ChatRequest is a request for a streamed chat completion.
+type ChatRequest struct {
+	Model    string
+	Messages []ChatMessage
+	Params   Params
+	// Tools are offered to providers that support tool calling.
+	Tools []ToolDefinition
+	// ResponseFormat asks for a reply matching a JSON schema.
+	ResponseFormat *ResponseFormat
 }
 
-func SendToAI(message, currentProvider string, apiKeys map[string]string) tea.Cmd {
-	provider := Providers[currentProvider]
-	apiKey := apiKeys[currentProvider]
+// ResponseFormat constrains a reply to JSON matching Schema. Providers
+// without native support get the schema as an instruction instead.
+type ResponseFormat struct {
+	Name   string
+	Schema json.RawMessage
+}
+
+// ModelInfo describes a model offered by a provider.
+type ModelInfo struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	ContextLength int    `json:"context_length,omitempty"`
+	// Pricing is nil when the provider does not publish prices.
+	Pricing   *Price `json:"pricing,omitempty"`
+	Vision    bool   `json:"vision,omitempty"`
+	Tools     bool   `json:"tools,omitempty"`
+	Reasoning bool   `json:"reasoning,omitempty"`
+	// JSONSchema is set for models that accept a response schema.
+	JSONSchema bool `json:"json_schema,omitempty"`
+	// Features is set when the listing reported the model's supported
+	// parameters, so that Tools and JSONSchema can be trusted.
+	Features bool `json:"features,omitempty"`
+}
+
+// Capabilities lists the optional features a provider supports.
+type Capabilities struct {
+	Streaming    bool
+	SystemPrompt bool
+	Vision       bool
+	Tools        bool
+	ListModels   bool
+	// JSONSchema is set for providers that accept a response schema.
+	JSONSchema bool
+}
 
-	switch currentProvider {
-	case "openrouter":
-		return sendToOpenRouter(message, provider, apiKey)
-	default:
-		return func() tea.Msg {
-			return types.ErrorMsg("Unknown provider: " + currentProvider)
+// Provider is a chat backend. Implementations register themselves with
+// Register so that they are picked up by SendToAI and the UI.
+type Provider interface {
+	// Name returns the human-readable provider name.
+	Name() string
+	// DefaultModel returns the model used when none is selected.
+	DefaultModel() string
+	Capabilities() Capabilities
+	// StreamChat sends the request and returns the stream of reply events.
+	// It must not block; cancelling ctx stops the request.
+	StreamChat(ctx context.Context, req ChatRequest) <-chan StreamEvent
+	ListModels() ([]ModelInfo, error)
+}
+
+// endpoint holds the connection settings of an HTTP provider.
+type endpoint struct {
+	Name    string
+	APIKey  string
+	BaseURL string
+	Model   string
+}
+
+// Registration describes how to construct a provider.
+type Registration struct {
+	// ID identifies the provider in the UI and in the API key map.
+	ID string
+	// KeyEnv is the environment variable holding the API key. Providers
+	// that need no key leave it empty.
+	KeyEnv string
+	// Local providers run on this machine and need no API key. They are
+	// offered once DiscoverLocalModels finds installed models.
+	Local bool
+	// New creates the provider with the configured API key.
+	New func(apiKey string) Provider
+}
+
+var registry []Registration
+
+// Register adds a provider backend. Registering an existing ID replaces it.
+func Register(reg Registration) {
+	for i, existing := range registry {
+		if existing.ID == reg.ID {
+			registry[i] = reg
+			return
 		}
 	}
+	registry = append(registry, reg)
 }
 
-func sendToOpenRouter(message string, provider types.AIProvider, apiKey string) tea.Cmd {
-	return func() tea.Msg {
-		requestBody := map[string]interface{}{
-			"model": provider.Model,
-			"messages": []map[string]string{
-				{"role": "user", "content": message},
-			},
-			"max_tokens": 1000,
-			"stream":     true,
+// Registered returns all provider registrations in registration order.
+func Registered() []Registration {
+	return append([]Registration(nil), registry...)
+}
+
+// KeyEnvVars maps provider IDs to the environment variables holding their
+// API keys.
+func KeyEnvVars() map[string]string {
+	envVars := make(map[string]string)
+	for _, reg := range registry {
+		if reg.KeyEnv != "" {
+			envVars[reg.ID] = reg.KeyEnv
 		}
+	}
+	return envVars
+}
 
-		jsonBody, err := json.Marshal(requestBody)
-		if err != nil {
-			return types.ErrorMsg("Failed to encode OpenAI request: " + err.Error())
+// AvailableProviders returns the IDs of remote providers that can be used
+// with the given API keys, in registration order.
+func AvailableProviders(apiKeys map[string]string) []string {
+	var available []string
+	for _, reg := range registry {
+		if !reg.Local && (reg.KeyEnv == "" || apiKeys[reg.ID] != "") {
+			available = append(available, reg.ID)
 		}
+	}
+	return available
+}
 
-		req, err := http.NewRequest("POST", provider.BaseURL, bytes.NewBuffer(jsonBody))
+// DiscoverLocalModels asks every local provider for its installed models and
+// returns them as "provider/model" targets. Providers that are not running
+// are skipped.
+func DiscoverLocalModels() []string {
+	var targets []string
+	for _, reg := range registry {
+		if !reg.Local {
+			continue
+		}
+		models, err := reg.New("").ListModels()
 		if err != nil {
-			return types.ErrorMsg("Failed to create OpenAI request: " + err.Error())
+			continue
 		}
+		for _, model := range models {
+			targets = append(targets, reg.ID+"/"+model.ID)
+		}
+	}
+	return targets
+}
 
-		req.Header.Set("Content-Type", "application/json")
-		req.Header.Set("Authorization", "Bearer "+apiKey)
+// SplitTarget splits a "provider/model" target into the provider ID and the
+// model. A bare provider ID yields an empty model.
+func SplitTarget(target string) (string, string) {
+	id, model, _ := strings.Cut(target, "/")
+	return id, model
+}
 
-		client := &http.Client{Timeout: 60 * time.Second}
-		resp, err := client.Do(req)
-		if err != nil {
-			return types.ErrorMsg("OpenAI API error: " + err.Error())
-		}
+// GetProvider creates the provider for target, which is either a provider ID
+// or a "provider/model" pair.
+func GetProvider(target string, apiKeys map[string]string) (Provider, bool) {
+	id, _ := SplitTarget(target)
+	reg, ok := registration(id)
+	if !ok {
+		return nil, false
+	}
+	return reg.New(apiKeys[id]), true
+}
 
-		if resp.StatusCode != http.StatusOK {
-			resp.Body.Close()
-			return types.ErrorMsg(fmt.Sprintf("OpenAI API returned status %d", resp.StatusCode))
+func registration(id string) (Registration, bool) {
+	for _, reg := range registry {
+		if reg.ID == id {
+			return reg, true
 		}
+	}
+	return Registration{}, false
+}
+
+// DefaultModel returns the model used for target: the pinned model of a
+// "provider/model" pair, or the provider's default model.
+func DefaultModel(target string) string {
+	if _, model := SplitTarget(target); model != "" {
+		return model
+	}
+	if provider, ok := GetProvider(target, nil); ok {
+		return provider.DefaultModel()
+	}
+	return ""
+}
 
-		go handleOpenRouterStream(resp.Body)
-		return nil
+// SupportsVision reports whether the model of target accepts images, going
+// by its cached catalog entry when there is one and by the provider's
+// capabilities otherwise.
+func SupportsVision(target string) bool {
+	id, _ := SplitTarget(target)
+	if info, ok := CachedModel(id, DefaultModel(target)); ok {
+		return info.Vision
 	}
+	provider, ok := GetProvider(target, nil)
+	return ok && provider.Capabilities().Vision
 }
 
-func handleOpenRouterStream(body io.ReadCloser) {
-	defer body.Close()
-	
-	program := types.GetGlobalProgram()
-	if program == nil {
-		return
+// SupportsTools reports whether the model of target accepts tool
+// definitions, going by its cached catalog entry when that lists the
+// model's parameters and by the provider's capabilities otherwise.
+func SupportsTools(target string) bool {
+	id, _ := SplitTarget(target)
+	if info, ok := CachedModel(id, DefaultModel(target)); ok && info.Features {
+		return info.Tools
 	}
+	provider, ok := GetProvider(target, nil)
+	return ok && provider.Capabilities().Tools
+}
+
+// SupportsJSONSchema reports whether the model of target accepts a response
+// schema, like SupportsTools.
+func SupportsJSONSchema(target string) bool {
+	id, _ := SplitTarget(target)
+	if info, ok := CachedModel(id, DefaultModel(target)); ok && info.Features {
+		return info.JSONSchema
+	}
+	provider, ok := GetProvider(target, nil)
+	return ok && provider.Capabilities().JSONSchema
+}
+
+// SendToAI streams a reply to req from the current provider target, which
+// also picks the model. If it fails before replying, the configured
+// fallbacks are tried in order; see SetFallbacks. Cancelling ctx stops the
+// request and closes the returned channel.
+func SendToAI(ctx context.Context, currentProvider string, req ChatRequest, apiKeys map[string]string) <-chan StreamEvent {
+	chain := fallbackChain(currentProvider, apiKeys)
 
-	scanner := bufio.NewScanner(body)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				program.Send(types.StreamEndMsg{})
-				return
+	return newStream(ctx, func(emit emitFunc) error {
+		var err error
+		for i, target := range chain {
+			if i > 0 && !emit(StreamEvent{Type: EventFallback, Target: target, Err: err}) {
+				return nil
 			}
 
-			var chunk struct {
-				Choices []struct {
-					Delta struct {
-						Content string `json:"content"`
-					} `json:"delta"`
-				} `json:"choices"`
+			var replied bool
+			replied, err = relayStream(ctx, emit, target, req, apiKeys)
+			if err == nil || replied || !canFallBack(err) {
+				return err
+			}
+		}
+		return err
+	})
+}
+
+// StreamTarget streams a reply to req from target alone, without trying the
+// fallbacks, so that the reply is known to come from that model.
+func StreamTarget(ctx context.Context, target string, req ChatRequest, apiKeys map[string]string) <-chan StreamEvent {
+	return newStream(ctx, func(emit emitFunc) error {
+		_, err := relayStream(ctx, emit, target, req, apiKeys)
+		return err
+	})
+}
+
+// relayStream forwards the reply of target to emit, leaving the final event
+// to the caller. It reports whether any part of the reply was forwarded.
+func relayStream(ctx context.Context, emit emitFunc, target string, req ChatRequest, apiKeys map[string]string) (bool, error) {
+	provider, ok := GetProvider(target, apiKeys)
+	if !ok {
+		return false, fmt.Errorf("Unknown provider: %s", target)
+	}
+
+	req.Model = DefaultModel(target)
+	if !SupportsTools(target) {
+		req.Tools = nil
+	}
+	if req.ResponseFormat != nil && !SupportsJSONSchema(target) {
+		req.Messages = append(append([]ChatMessage(nil), req.Messages...), ChatMessage{
+			Role:    RoleSystem,
+			Content: "Reply with only a JSON value, without Markdown fences or other text, that matches this JSON schema:\n" + string(req.ResponseFormat.Schema),
+		})
+		req.ResponseFormat = nil
+	}
+	if hasImages(req.Messages) && !SupportsVision(target) {
+		req.Messages = withoutImages(req.Messages)
+	}
+
+	replied := false
+	for event := range provider.StreamChat(ctx, req) {
+		switch event.Type {
+		case EventDone:
+			return replied, nil
+		case EventError:
+			return replied, event.Err
+		case EventDelta, EventReasoning, EventToolCalls:
+			replied = true
+		}
+		if !emit(event) {
+			return replied, ctx.Err()
+		}
+	}
+	return replied, ctx.Err()
+}
+
+func hasImages(history []ChatMessage) bool {
+	for _, msg := range history {
+		for _, part := range msg.Parts {
+			if part.Type == PartImage {
+				return true
 			}
+		}
+	}
+	return false
+}
 
-			if err := json.Unmarshal([]byte(data), &chunk); err == nil {
-				if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
-					program.Send(types.StreamCharMsg(chunk.Choices[0].Delta.Content))
+// withoutImages replaces image parts with a note, for models that cannot
+// see them, so that a conversation can move to such a model.
+func withoutImages(history []ChatMessage) []ChatMessage {
+	messages := make([]ChatMessage, 0, len(history))
+	for _, msg := range history {
+		if len(msg.Parts) > 0 {
+			parts := make([]ContentPart, 0, len(msg.Parts))
+			for _, part := range msg.Parts {
+				if part.Type == PartImage {
+					part = ContentPart{Type: PartText, Text: "[An image was attached here, but this model cannot view images.]"}
 				}
+				parts = append(parts, part)
 			}
+			msg.Parts = parts
 		}
+		messages = append(messages, msg)
 	}
+	return messages
+}
+
+// plainMessages rewrites tool turns as ordinary text for providers without
+// tool calling, so a conversation started elsewhere can continue. Text
+// parts are appended to the content; image parts are dropped.
+func plainMessages(history []ChatMessage) []ChatMessage {
+	messages := make([]ChatMessage, 0, len(history))
+	for _, msg := range history {
+		for _, part := range msg.Parts {
+			if part.Type == PartText {
+				msg.Content += "\n\n" + part.Text
+			}
+		}
+		msg.Parts = nil
 
-	program.Send(types.StreamEndMsg{})
-}
\ No newline at end of file
+		switch {
+		case msg.Role == RoleTool:
+			msg = ChatMessage{Role: RoleUser, Content: "Tool result:\n" + msg.Content}
+		case len(msg.ToolCalls) > 0:
+			content := msg.Content
+			for _, call := range msg.ToolCalls {
+				content += fmt.Sprintf("\n[called %s(%s)]", call.Name, call.Arguments)
+			}
+			msg = ChatMessage{Role: msg.Role, Content: strings.TrimSpace(content)}
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxAttempts is the total number of tries for one request.
+	maxAttempts = 4
+	// baseRetryDelay is the backoff before the first retry; it doubles for
+	// every further attempt.
+	baseRetryDelay = time.Second
+	// maxRetryDelay caps the backoff. A Retry-After longer than this is not
+	// worth waiting for, so the error is reported instead.
+	maxRetryDelay = 30 * time.Second
+)
+
+// sendWithRetry sends the request built by newRequest, retrying transient
+// failures (connection errors, rate limits and server errors) with jittered
+// exponential backoff and honouring Retry-After. Each retry is announced with
+// an EventRetry event. It returns the first 200 response; since no reply
+// tokens have been read at that point, a retry never repeats streamed text.
+func sendWithRetry(ctx context.Context, client *http.Client, provider string, newRequest func() (*http.Request, error), emit emitFunc) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+
+		var perr *ProviderError
+		resp, err := client.Do(req)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			perr = networkError(provider, err)
+		case resp.StatusCode != http.StatusOK:
+			perr = parseErrorResponse(provider, resp)
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+
+		delay, retry := retryDelay(perr, attempt)
+		if !retry {
+			return nil, perr
+		}
+
+		event := StreamEvent{Type: EventRetry, Err: perr, Attempt: attempt + 1, MaxAttempts: maxAttempts, Delay: delay}
+		if !emit(event) {
+			return nil, ctx.Err()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryDelay decides whether a failed attempt is retried and how long to
+// wait first.
+func retryDelay(perr *ProviderError, attempt int) (time.Duration, bool) {
+	if attempt >= maxAttempts || !isTransient(perr) {
+		return 0, false
+	}
+
+	if perr.RetryAfter > 0 {
+		return perr.RetryAfter, perr.RetryAfter <= maxRetryDelay
+	}
+
+	backoff := baseRetryDelay << (attempt - 1)
+	if backoff > maxRetryDelay {
+		backoff = maxRetryDelay
+	}
+	// Equal jitter, a random delay between half and all of the backoff,
+	// keeps clients from retrying in lockstep
+	return backoff/2 + rand.N(backoff/2+1), true
+}
+
+func isTransient(perr *ProviderError) bool {
+	switch perr.Kind {
+	case ErrorNetwork, ErrorRateLimit, ErrorServer:
+		return !errors.Is(perr.Err, context.Canceled)
+	}
+	return false
+}
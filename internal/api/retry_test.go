@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		// want is the expected wait; HTTP dates have whole seconds, so a
+		// result up to two seconds shorter is accepted for them
+		want time.Duration
+		date bool
+	}{
+		{"none", nil, 0, false},
+		{"seconds", map[string]string{"Retry-After": "7"}, 7 * time.Second, false},
+		{"above the cap", map[string]string{"Retry-After": "120"}, 120 * time.Second, false},
+		{"zero", map[string]string{"Retry-After": "0"}, 0, false},
+		{"negative", map[string]string{"Retry-After": "-3"}, 0, false},
+		{"garbage", map[string]string{"Retry-After": "soon"}, 0, false},
+		{"milliseconds", map[string]string{"retry-after-ms": "1500", "Retry-After": "9"}, 1500 * time.Millisecond, false},
+		{"http date", map[string]string{"Retry-After": time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)}, 10 * time.Second, true},
+		{"past http date", map[string]string{"Retry-After": time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)}, 0, false},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		for key, value := range tt.header {
+			header.Set(key, value)
+		}
+		got := parseRetryAfter(header)
+		if tt.date {
+			if got > tt.want || got < tt.want-2*time.Second {
+				t.Errorf("%s: parseRetryAfter = %v, want about %v", tt.name, got, tt.want)
+			}
+		} else if got != tt.want {
+			t.Errorf("%s: parseRetryAfter = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		perr    *ProviderError
+		attempt int
+		// want is the delay, which only matters when the error is retried
+		want  time.Duration
+		retry bool
+	}{
+		{"retry after", &ProviderError{Kind: ErrorRateLimit, RetryAfter: 5 * time.Second}, 1, 5 * time.Second, true},
+		{"retry after at the cap", &ProviderError{Kind: ErrorRateLimit, RetryAfter: maxRetryDelay}, 1, maxRetryDelay, true},
+		{"retry after above the cap", &ProviderError{Kind: ErrorRateLimit, RetryAfter: maxRetryDelay + time.Second}, 1, 0, false},
+		{"last attempt", &ProviderError{Kind: ErrorServer, RetryAfter: time.Second}, maxAttempts, 0, false},
+		{"bad request", &ProviderError{Kind: ErrorBadRequest}, 1, 0, false},
+		{"auth", &ProviderError{Kind: ErrorAuth}, 1, 0, false},
+		{"quota", &ProviderError{Kind: ErrorQuota}, 1, 0, false},
+		{"cancelled", &ProviderError{Kind: ErrorNetwork, Err: fmt.Errorf("post: %w", context.Canceled)}, 1, 0, false},
+	}
+	for _, tt := range tests {
+		got, retry := retryDelay(tt.perr, tt.attempt)
+		if retry != tt.retry || (retry && got != tt.want) {
+			t.Errorf("%s: retryDelay = %v, %v, want %v, %v", tt.name, got, retry, tt.want, tt.retry)
+		}
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+	for _, kind := range []ErrorKind{ErrorNetwork, ErrorRateLimit, ErrorServer} {
+		for attempt := 1; attempt < maxAttempts; attempt++ {
+			backoff := baseRetryDelay << (attempt - 1)
+			for i := 0; i < 100; i++ {
+				delay, retry := retryDelay(&ProviderError{Kind: kind}, attempt)
+				if !retry || delay < backoff/2 || delay > backoff {
+					t.Fatalf("%s attempt %d: retryDelay = %v, %v, want a retry within [%v, %v]", kind, attempt, delay, retry, backoff/2, backoff)
+				}
+			}
+		}
+	}
+}
+
+func TestSendWithRetry(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("retry-after-ms", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	var retries []StreamEvent
+	newRequest := func() (*http.Request, error) { return http.NewRequest("GET", server.URL, nil) }
+	resp, err := sendWithRetry(context.Background(), server.Client(), "Test", newRequest, func(event StreamEvent) bool {
+		retries = append(retries, event)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("sendWithRetry: %v", err)
+	}
+	resp.Body.Close()
+
+	if calls != 3 || len(retries) != 2 {
+		t.Fatalf("%d calls and %d retry events, want 3 and 2", calls, len(retries))
+	}
+	for i, event := range retries {
+		if event.Type != EventRetry || event.Attempt != i+2 || event.MaxAttempts != maxAttempts || event.Delay != time.Millisecond {
+			t.Errorf("retry event %d = %+v", i, event)
+		}
+	}
+}
+
+func TestSendWithRetryGivesUp(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"type":"invalid_request_error","message":"bad"}}`)
+	}))
+	defer server.Close()
+
+	newRequest := func() (*http.Request, error) { return http.NewRequest("GET", server.URL, nil) }
+	_, err := sendWithRetry(context.Background(), server.Client(), "Test", newRequest, func(StreamEvent) bool { return true })
+	if err == nil || calls != 1 {
+		t.Errorf("err = %v after %d calls, want a bad request error after 1", err, calls)
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// EventType identifies the kind of a StreamEvent.
+type EventType int
+
+const (
+	// EventDelta carries the next chunk of the reply in Text.
+	EventDelta EventType = iota
+	// EventDone reports that the reply finished.
+	EventDone
+	// EventError reports in Err why the reply failed.
+	EventError
+	// EventRetry reports that the request failed with Err before any reply
+	// arrived and is retried as Attempt of MaxAttempts after Delay.
+	EventRetry
+	// EventUsage carries the token counts of the reply in Usage.
+	EventUsage
+	// EventFallback reports that the previous target failed with Err before
+	// replying and the request moved on to Target.
+	EventFallback
+	// EventToolCalls carries the tools the reply asks to run in ToolCalls.
+	// It is sent once, after the last delta.
+	EventToolCalls
+	// EventTruncated reports that the reply was cut off at the token limit.
+	EventTruncated
+	// EventReasoning carries the next chunk of the model's reasoning in
+	// Text. It is shown to the user but is not part of the reply. An event
+	// with a Signature instead signs the reasoning so far, which must then be
+	// sent back with it as ChatMessage.ReasoningSignature.
+	EventReasoning
+)
+
+// Usage is the token accounting of one reply. PromptTokens includes the
+// CachedTokens served from the provider's prompt cache.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	CachedTokens     int
+}
+
+// StreamEvent is a single event of a streamed reply. Every stream ends with
+// exactly one EventDone or EventError before its channel is closed, unless
+// the stream was cancelled.
+type StreamEvent struct {
+	Type      EventType
+	Text      string
+	Err       error
+	Usage     *Usage
+	ToolCalls []ToolCall
+	Signature string
+
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration
+
+	Target string
+}
+
+// streamBuffer lets a provider read ahead of a slow consumer.
+const streamBuffer = 64
+
+// emitFunc delivers an event to the consumer. It returns false once the
+// stream has been cancelled, so the producer can stop early.
+type emitFunc func(StreamEvent) bool
+
+// newStream runs produce in a goroutine and returns the channel its events
+// are delivered on. produce emits the reply deltas; its return value decides
+// whether the stream ends with EventDone or EventError. Nothing is sent after
+// ctx is cancelled.
+func newStream(ctx context.Context, produce func(emit emitFunc) error) <-chan StreamEvent {
+	events := make(chan StreamEvent, streamBuffer)
+
+	go func() {
+		defer close(events)
+
+		emit := func(event StreamEvent) bool {
+			if ctx.Err() != nil {
+				return false
+			}
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		err := produce(emit)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			emit(StreamEvent{Type: EventError, Err: err})
+			return
+		}
+		emit(StreamEvent{Type: EventDone})
+	}()
+
+	return events
+}
+
+// delta emits a text chunk, skipping empty ones.
+func delta(emit emitFunc, text string) bool {
+	if text == "" {
+		return true
+	}
+	return emit(StreamEvent{Type: EventDelta, Text: text})
+}
+
+// reasoning emits a chunk of reasoning, skipping empty ones.
+func reasoning(emit emitFunc, text string) bool {
+	if text == "" {
+		return true
+	}
+	return emit(StreamEvent{Type: EventReasoning, Text: text})
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNewStreamEndsWithDone(t *testing.T) {
+	events := collect(newStream(context.Background(), func(emit emitFunc) error {
+		delta(emit, "a")
+		delta(emit, "")
+		reasoning(emit, "b")
+		return nil
+	}))
+
+	want := []EventType{EventDelta, EventReasoning, EventDone}
+	if len(events) != len(want) {
+		t.Fatalf("events = %+v, want types %v", events, want)
+	}
+	for i, event := range events {
+		if event.Type != want[i] {
+			t.Errorf("event %d = %v, want %v", i, event.Type, want[i])
+		}
+	}
+}
+
+func TestNewStreamEndsWithError(t *testing.T) {
+	failure := errors.New("boom")
+	events := collect(newStream(context.Background(), func(emit emitFunc) error {
+		delta(emit, "partial")
+		return failure
+	}))
+
+	if len(events) != 2 || events[1].Type != EventError || !errors.Is(events[1].Err, failure) {
+		t.Errorf("events = %+v, want a delta and the error", events)
+	}
+}
+
+func TestNewStreamCancelClosesChannel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	emitted := make(chan bool)
+	stopped := make(chan struct{})
+
+	events := newStream(ctx, func(emit emitFunc) error {
+		defer close(stopped)
+		// Fill the buffer so that the next emit has to wait for the consumer
+		for i := 0; i < streamBuffer; i++ {
+			emit(StreamEvent{Type: EventDelta, Text: "x"})
+		}
+		emitted <- true
+		if emit(StreamEvent{Type: EventDelta, Text: "blocked"}) {
+			t.Error("emit succeeded after cancel")
+		}
+		if emit(StreamEvent{Type: EventDelta, Text: "after"}) {
+			t.Error("emit succeeded after cancel")
+		}
+		return errors.New("not reported after cancel")
+	})
+
+	<-emitted
+	cancel()
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("producer did not stop after cancel")
+	}
+
+	count := 0
+	for event := range events {
+		if event.Type != EventDelta || event.Text != "x" {
+			t.Errorf("event after cancel: %+v", event)
+		}
+		count++
+	}
+	if count != streamBuffer {
+		t.Errorf("got %d buffered events, want %d", count, streamBuffer)
+	}
+}
+
+func TestNewStreamCancelledBeforeStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	events := collect(newStream(ctx, func(emit emitFunc) error {
+		if emit(StreamEvent{Type: EventDelta, Text: "x"}) {
+			t.Error("emit succeeded on a cancelled stream")
+		}
+		return nil
+	}))
+	if len(events) != 0 {
+		t.Errorf("events = %+v, want none", events)
+	}
+}
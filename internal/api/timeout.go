@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultStreamTimeout is how long a server may stay silent before a
+	// streamed reply is given up, not how long the reply may take
+	defaultStreamTimeout = 60 * time.Second
+	// defaultListTimeout bounds a whole model listing
+	defaultListTimeout = 30 * time.Second
+)
+
+// streamClient returns a client for streamed replies. timeout limits how
+// long the server may stay silent, first while the response headers are
+// awaited and then between reads of the body, so a long reply that keeps
+// arriving is never cut off. The request's context cancels it at any point.
+func streamClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: idleTimeout{timeout: timeout}}
+}
+
+// idleTimeout is a RoundTripper that cancels a request once it has waited
+// timeout for the server.
+type idleTimeout struct {
+	timeout time.Duration
+}
+
+func (t idleTimeout) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	body := &idleBody{timeout: t.timeout, cancel: cancel}
+	body.timer = time.AfterFunc(t.timeout, body.expire)
+
+	resp, err := http.DefaultTransport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		body.timer.Stop()
+		cancel()
+		if body.expired.Load() {
+			return nil, fmt.Errorf("no response within %s", t.timeout)
+		}
+		return nil, err
+	}
+	body.ReadCloser = resp.Body
+	resp.Body = body
+	return resp, nil
+}
+
+// idleBody is a response body whose timer is reset by every read.
+type idleBody struct {
+	io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+	expired atomic.Bool
+}
+
+func (b *idleBody) expire() {
+	b.expired.Store(true)
+	b.cancel()
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && b.expired.Load() {
+		return n, fmt.Errorf("the stream stalled: no data for %s", b.timeout)
+	}
+	b.timer.Reset(b.timeout)
+	return n, err
+}
+
+func (b *idleBody) Close() error {
+	b.timer.Stop()
+	b.cancel()
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamClientOutlastsSteadyStreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 6; i++ {
+			fmt.Fprintf(w, "chunk %d\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := streamClient(50 * time.Millisecond).Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading a stream that outlasts the timeout: %v", err)
+	}
+	if strings.Count(string(body), "chunk") != 6 {
+		t.Errorf("body = %q, want 6 chunks", body)
+	}
+}
+
+func TestStreamClientGivesUpOnStalls(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/headers" {
+			<-release
+			return
+		}
+		fmt.Fprint(w, "chunk\n")
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := streamClient(50 * time.Millisecond)
+	if _, err := client.Get(server.URL + "/headers"); err == nil || !strings.Contains(err.Error(), "no response within") {
+		t.Errorf("Get = %v, want a header timeout", err)
+	}
+
+	resp, err := client.Get(server.URL + "/body")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); err == nil || !strings.Contains(err.Error(), "stalled") {
+		t.Errorf("reading a stalled body = %v, want a stall error", err)
+	}
+}
+
+func TestStreamClientHonoursCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := streamClient(time.Minute).Do(req); err == nil {
+		t.Error("Do succeeded after cancel")
+	}
+}
@@ -1,6 +1,7 @@
 package app
 
 import (
+	"Chat2/internal/api"
 	"Chat2/internal/config"
 	"Chat2/internal/types"
 	"Chat2/internal/ui/views"
@@ -14,9 +15,36 @@ type App struct {
 	apiKeys  map[string]string
 }
 
-func New() *App {
-	apiKeys := config.LoadAPIKeys()
-	model := views.NewMainView(apiKeys)
+// Options are the launch flags.
+type Options struct {
+	// Continue reopens the most recently updated session.
+	Continue bool
+}
+
+func New(opts Options) *App {
+	cfg, cfgErr := config.Load()
+	var providerErrs []error
+	for _, provider := range cfg.Providers {
+		if err := api.RegisterOpenAICompatible(provider); err != nil {
+			providerErrs = append(providerErrs, err)
+		}
+	}
+	for model, price := range cfg.Prices {
+		api.SetPrice(model, api.PriceFromConfig(price))
+	}
+	api.SetFallbacks(cfg.Fallbacks)
+
+	apiKeys := config.LoadAPIKeys(api.KeyEnvVars())
+	model := views.NewMainView(cfg, apiKeys)
+	if cfgErr != nil {
+		model.AddMessage(types.NewError(cfgErr.Error()))
+	}
+	for _, err := range providerErrs {
+		model.AddMessage(types.NewError(err.Error()))
+	}
+	if opts.Continue {
+		model.ContinueLatest()
+	}
 	
 	return &App{
 		model:   model,
@@ -26,10 +54,7 @@ func New() *App {
 
 func (a *App) Start() error {
 	a.program = tea.NewProgram(a.model, tea.WithAltScreen())
-	
-	// Set global program for streaming responses
-	types.SetGlobalProgram(a.program)
-	
+
 	_, err := a.program.Run()
 	return err
 }
@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"Chat2/internal/types"
+)
+
+const (
+	// MaxImageBytes is the largest image that can be attached; providers
+	// reject bigger ones.
+	MaxImageBytes = 5 * 1024 * 1024
+	// MaxTextBytes is the largest text file that can be attached, since
+	// its content is inlined in the prompt.
+	MaxTextBytes = 100 * 1024
+)
+
+// imageTypes are the image formats vision models accept, by extension.
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// LoadAttachment reads the file at path for attaching to a message. It
+// accepts PNG, JPEG, GIF and WebP images and UTF-8 text files within the
+// size limits.
+func LoadAttachment(path string) (types.Attachment, error) {
+	if rest, ok := strings.CutPrefix(path, "~"+string(filepath.Separator)); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, rest)
+		}
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return types.Attachment{}, err
+	}
+	name := filepath.Base(path)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return types.Attachment{}, err
+	}
+	if info.IsDir() {
+		return types.Attachment{}, fmt.Errorf("%s is a directory", name)
+	}
+
+	mediaType, isImage := imageTypes[strings.ToLower(filepath.Ext(path))]
+	if isImage && info.Size() > MaxImageBytes {
+		return types.Attachment{}, fmt.Errorf("%s is %s; images are limited to %s", name, FormatSize(info.Size()), FormatSize(MaxImageBytes))
+	}
+	if !isImage && info.Size() > MaxTextBytes {
+		return types.Attachment{}, fmt.Errorf("%s is %s; text files are limited to %s", name, FormatSize(info.Size()), FormatSize(MaxTextBytes))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return types.Attachment{}, err
+	}
+
+	if !isImage {
+		if strings.HasPrefix(http.DetectContentType(data), "image/") {
+			return types.Attachment{}, fmt.Errorf("%s is not a supported image; use PNG, JPEG, GIF or WebP", name)
+		}
+		if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+			return types.Attachment{}, fmt.Errorf("%s is neither a text file nor a supported image", name)
+		}
+		mediaType = "text/plain"
+	}
+
+	return types.Attachment{Name: name, Path: path, MediaType: mediaType, Data: data}, nil
+}
+
+// FormatSize renders a byte count for display, such as "12 KB".
+func FormatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%d KB", size/1024)
+	}
+	return fmt.Sprintf("%d B", size)
+}
@@ -0,0 +1,131 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+
+	"Chat2/internal/api"
+)
+
+const (
+	// perMessageOverhead approximates the tokens a provider spends on role
+	// markers and separators for each message.
+	perMessageOverhead = 4
+	// summarySnippetLength caps how much of each dropped question is kept in
+	// the summary of omitted turns.
+	summarySnippetLength = 80
+	// imageTokens approximates what a provider charges for an attached
+	// image, which varies with its size.
+	imageTokens = 1500
+)
+
+// EstimateTokens gives a rough token count for text, using the common
+// heuristic of four characters per token.
+func EstimateTokens(text string) int {
+	return (len(text)+3)/4 + perMessageOverhead
+}
+
+// messageTokens estimates the tokens of a message including its tool calls
+// and attachments.
+func messageTokens(msg api.ChatMessage) int {
+	text := msg.Content
+	for _, call := range msg.ToolCalls {
+		text += call.Name + call.Arguments
+	}
+	images := 0
+	for _, part := range msg.Parts {
+		if part.Type == api.PartImage {
+			images++
+		} else {
+			text += part.Text
+		}
+	}
+	return EstimateTokens(text) + images*imageTokens
+}
+
+// fitToBudget keeps the system messages and the most recent turns that fit in
+// budget tokens. Older turns are replaced by a short system summary listing
+// the questions that were dropped. The kept turns start at a user turn, and
+// the latest exchange is kept whole even when it alone exceeds the budget.
+func fitToBudget(system, turns []api.ChatMessage, budget int) []api.ChatMessage {
+	used := 0
+	for _, msg := range system {
+		used += EstimateTokens(msg.Content)
+	}
+
+	start := len(turns)
+	for start > 0 {
+		cost := messageTokens(turns[start-1])
+		if used+cost > budget && start < len(turns) {
+			break
+		}
+		used += cost
+		start--
+	}
+
+	if start > 0 {
+		// Reserve room for the summary by dropping further turns if needed
+		for start < len(turns)-1 && used+EstimateTokens(summarize(turns[:start])) > budget {
+			used -= messageTokens(turns[start])
+			start++
+		}
+		// Providers expect the first kept turn to be the user's, which also
+		// keeps tool results with the assistant turn that asked for them
+		if next := nextUserTurn(turns, start); next >= 0 {
+			start = next
+		} else {
+			start = lastUserTurn(turns, start)
+		}
+	}
+
+	history := make([]api.ChatMessage, 0, len(system)+len(turns)-start+1)
+	history = append(history, system...)
+	if start > 0 {
+		history = append(history, api.ChatMessage{Role: api.RoleSystem, Content: summarize(turns[:start])})
+	}
+	return append(history, turns[start:]...)
+}
+
+func nextUserTurn(turns []api.ChatMessage, from int) int {
+	for i := from; i < len(turns); i++ {
+		if turns[i].Role == api.RoleUser {
+			return i
+		}
+	}
+	return -1
+}
+
+// lastUserTurn returns the index of the last user turn at or before from,
+// or 0 if there is none.
+func lastUserTurn(turns []api.ChatMessage, from int) int {
+	for i := from; i > 0; i-- {
+		if turns[i].Role == api.RoleUser {
+			return i
+		}
+	}
+	return 0
+}
+
+func summarize(dropped []api.ChatMessage) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d earlier messages were omitted to fit the context window.", len(dropped))
+
+	var questions []string
+	for _, msg := range dropped {
+		if msg.Role != api.RoleUser {
+			continue
+		}
+		snippet := strings.Join(strings.Fields(msg.Content), " ")
+		if runes := []rune(snippet); len(runes) > summarySnippetLength {
+			snippet = string(runes[:summarySnippetLength]) + "…"
+		}
+		questions = append(questions, "- "+snippet)
+	}
+
+	if len(questions) > 0 {
+		b.WriteString(" The user had previously asked:\n")
+		b.WriteString(strings.Join(questions, "\n"))
+	}
+
+	return b.String()
+}
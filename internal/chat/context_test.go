@@ -0,0 +1,103 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"Chat2/internal/api"
+)
+
+func TestFitToBudget(t *testing.T) {
+	system := []api.ChatMessage{{Role: api.RoleSystem, Content: "Be brief."}}
+	user := func(text string) api.ChatMessage { return api.ChatMessage{Role: api.RoleUser, Content: text} }
+	assistant := func(text string) api.ChatMessage { return api.ChatMessage{Role: api.RoleAssistant, Content: text} }
+	call := api.ChatMessage{Role: api.RoleAssistant, ToolCalls: []api.ToolCall{{ID: "t", Name: "read_file", Arguments: `{"path": "main.go"}`}}}
+	result := api.ChatMessage{Role: api.RoleTool, ToolCallID: "t", Content: strings.Repeat("x", 200)}
+	long := strings.Repeat("word ", 200)
+
+	tests := []struct {
+		name   string
+		system []api.ChatMessage
+		turns  []api.ChatMessage
+		budget int
+		// want lists the contents of the kept messages, with "summary" for
+		// the summary of dropped turns
+		want    []string
+		summary string
+	}{
+		{
+			name:   "empty history",
+			system: system,
+			budget: 100,
+			want:   []string{"Be brief."},
+		},
+		{
+			name:   "nothing at all",
+			budget: 100,
+			want:   []string{},
+		},
+		{
+			name:   "everything fits",
+			system: system,
+			turns:  []api.ChatMessage{user("Hi"), assistant("Hello")},
+			budget: 100,
+			want:   []string{"Be brief.", "Hi", "Hello"},
+		},
+		{
+			name:   "single oversized message",
+			turns:  []api.ChatMessage{user(long)},
+			budget: 10,
+			want:   []string{long},
+		},
+		{
+			name:    "summary in place of old turns",
+			system:  system,
+			turns:   []api.ChatMessage{user("First question"), assistant(long), user("Second"), assistant("Answer")},
+			budget:  80,
+			want:    []string{"Be brief.", "summary", "Second", "Answer"},
+			summary: "- First question",
+		},
+		{
+			name:   "cut moves forward to a user turn",
+			turns:  []api.ChatMessage{user("First"), assistant(long), user("Second"), assistant("A reply of some length here")},
+			budget: 20,
+			want:   []string{"summary", "Second", "A reply of some length here"},
+		},
+		{
+			name:   "tool loop is kept from its user turn",
+			turns:  []api.ChatMessage{user("Old"), assistant("Old answer"), user("Read main.go"), call, result, call, result, assistant("Done")},
+			budget: 80,
+			want:   []string{"summary", "Read main.go", "", result.Content, "", result.Content, "Done"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := fitToBudget(tt.system, tt.turns, tt.budget)
+
+			got := make([]string, 0, len(history))
+			for i, msg := range history {
+				if i == len(tt.system) && msg.Role == api.RoleSystem {
+					got = append(got, "summary")
+					if !strings.Contains(msg.Content, tt.summary) {
+						t.Errorf("summary = %q, want it to contain %q", msg.Content, tt.summary)
+					}
+					continue
+				}
+				got = append(got, msg.Content)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("history = %q, want %q", got, tt.want)
+			}
+
+			for _, msg := range history {
+				if msg.Role != api.RoleSystem {
+					if msg.Role != api.RoleUser {
+						t.Errorf("first kept turn is %s, want user", msg.Role)
+					}
+					break
+				}
+			}
+		})
+	}
+}
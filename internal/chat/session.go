@@ -1,56 +1,402 @@
 package chat
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
+	"time"
+
+	"Chat2/internal/api"
+	"Chat2/internal/types"
 )
 
+// DefaultSystemPrompt is sent as the first turn of every conversation.
+const DefaultSystemPrompt = "You are PUKU, a helpful AI assistant running in the user's terminal. Answer concisely and use Markdown code blocks for code."
+
 type Session struct {
-	Messages        []string
+	// ID names the session in the store; Title is empty until the
+	// session is named.
+	ID              string
+	Title           string
+	Created         time.Time
+	Updated         time.Time
+	Messages        []types.Message
 	CurrentProvider string
+	SystemPrompt    string
 	IsActive        bool
+	// Usage totals the token usage and cost of every reply.
+	Usage types.TokenUsage
+	// Params override the configured generation settings for this session.
+	Params api.Params
+	// response is the index of the message BeginResponse added. Notices
+	// may be appended while it streams, so it need not be the last one.
+	response int
 }
 
 func NewSession(provider string) *Session {
+	now := time.Now()
 	return &Session{
-		Messages:        []string{},
+		ID:              newSessionID(now),
+		Created:         now,
+		Updated:         now,
+		Messages:        []types.Message{},
 		CurrentProvider: provider,
+		SystemPrompt:    DefaultSystemPrompt,
 		IsActive:        false,
 	}
 }
 
-func (s *Session) AddMessage(message string) {
+func (s *Session) AddMessage(message types.Message) {
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
 	s.Messages = append(s.Messages, message)
 }
 
-func (s *Session) AddUserMessage(message string) {
-	s.Messages = append(s.Messages, "You: "+message)
+func (s *Session) AddUserMessage(message string, attachments ...types.Attachment) {
+	s.AddMessage(types.Message{Role: types.RoleUser, Content: message, Attachments: attachments})
 }
 
-func (s *Session) AddAIResponse(response string) {
-	filteredResponse := s.filterSystemReminders(response)
-	s.Messages = append(s.Messages, "AI: "+filteredResponse)
+func (s *Session) AddAIResponse(response, provider, model string) {
+	s.AddMessage(types.Message{
+		Role:     types.RoleAssistant,
+		Content:  s.filterSystemReminders(response),
+		Provider: provider,
+		Model:    model,
+	})
+}
+
+// AddComparison records a prompt that was sent to several models and the
+// reply the user kept. spent is the usage of every reply, kept or not, and
+// goes into the session totals.
+func (s *Session) AddComparison(prompt string, kept types.Message, spent types.TokenUsage) {
+	s.AddUserMessage(prompt)
+	kept.Role = types.RoleAssistant
+	kept.Content = s.filterSystemReminders(kept.Content)
+	s.AddMessage(kept)
+	if spent.TotalTokens() > 0 {
+		s.Usage.Add(spent)
+	}
+}
+
+func (s *Session) AddNotice(text string) {
+	s.AddMessage(types.NewNotice(text))
 }
 
 func (s *Session) AddErrorMessage(err string) {
-	s.Messages = append(s.Messages, "❌ Error: "+err)
+	s.AddMessage(types.NewError("Error: " + err))
+}
+
+// AddProviderError records a failed provider request with its details.
+func (s *Session) AddProviderError(message string, detail types.ErrorDetail) {
+	msg := types.NewError(message)
+	msg.Error = &detail
+	s.AddMessage(msg)
+}
+
+// BeginResponse appends an empty assistant message that collects streamed
+// chunks until FinishResponse is called.
+func (s *Session) BeginResponse(provider, model string) {
+	s.response = len(s.Messages)
+	s.AddMessage(types.Message{
+		Role:     types.RoleAssistant,
+		Provider: provider,
+		Model:    model,
+		Status:   types.StatusStreaming,
+	})
+}
+
+// FallBackResponse relabels the in-progress response after its provider
+// failed and provider/model took over.
+func (s *Session) FallBackResponse(provider, model string) {
+	msg := s.streamingMessage()
+	if msg == nil {
+		return
+	}
+	if msg.FallbackFrom == "" {
+		msg.FallbackFrom = msg.Provider
+	}
+	msg.Provider = provider
+	msg.Model = model
+}
+
+// AppendToResponse adds a streamed chunk to the in-progress response.
+func (s *Session) AppendToResponse(chunk string) {
+	if msg := s.streamingMessage(); msg != nil {
+		msg.Content += chunk
+	}
+}
+
+// AppendReasoning adds a streamed chunk of reasoning to the in-progress
+// response.
+func (s *Session) AppendReasoning(chunk string) {
+	if msg := s.streamingMessage(); msg != nil {
+		msg.Reasoning += chunk
+	}
+}
+
+// SetReasoningSignature records the provider's signature of the reasoning
+// of the in-progress response.
+func (s *Session) SetReasoningSignature(signature string) {
+	if msg := s.streamingMessage(); msg != nil {
+		msg.ReasoningSignature = signature
+	}
+}
+
+// SetResponseContent replaces the text of the in-progress response, such
+// as with a reformatted version of it.
+func (s *Session) SetResponseContent(content string) {
+	if msg := s.streamingMessage(); msg != nil {
+		msg.Content = content
+	}
+}
+
+// SetResponseSchema records the JSON schema the in-progress response was
+// validated against.
+func (s *Session) SetResponseSchema(name string) {
+	if msg := s.streamingMessage(); msg != nil {
+		msg.Schema = name
+	}
+}
+
+// MarkResponseTruncated records that the in-progress response was cut off
+// at the token limit.
+func (s *Session) MarkResponseTruncated() {
+	if msg := s.streamingMessage(); msg != nil {
+		msg.Truncated = true
+	}
+}
+
+// StreamingResponse returns the text received so far for the in-progress
+// response, or an empty string if nothing is streaming.
+func (s *Session) StreamingResponse() string {
+	if msg := s.streamingMessage(); msg != nil {
+		return msg.Content
+	}
+	return ""
+}
+
+// FinishResponse completes the in-progress response. An empty response is
+// removed from the session.
+func (s *Session) FinishResponse() {
+	msg := s.streamingMessage()
+	if msg == nil {
+		return
+	}
+
+	msg.Content = s.filterSystemReminders(msg.Content)
+	if msg.Content == "" && len(msg.ToolCalls) == 0 && msg.Reasoning == "" {
+		s.removeResponse()
+		return
+	}
+	msg.Status = types.StatusComplete
+}
+
+// SetResponseToolCalls records the tools the in-progress response asks to
+// run.
+func (s *Session) SetResponseToolCalls(calls []types.ToolCall) {
+	if msg := s.streamingMessage(); msg != nil {
+		msg.ToolCalls = calls
+	}
+}
+
+// BeginToolCall adds a running tool message for call.
+func (s *Session) BeginToolCall(call types.ToolCall) {
+	s.AddMessage(types.Message{
+		Role:   types.RoleTool,
+		Status: types.StatusRunning,
+		Tool:   &types.ToolResult{CallID: call.ID, Name: call.Name, Arguments: call.Arguments},
+	})
+}
+
+// AppendToolOutput adds output streamed by the running tool call callID.
+func (s *Session) AppendToolOutput(callID, chunk string) {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		msg := &s.Messages[i]
+		if msg.Role == types.RoleTool && msg.Tool != nil && msg.Tool.CallID == callID {
+			if msg.Status == types.StatusRunning {
+				msg.Content += chunk
+			}
+			return
+		}
+	}
+}
+
+// FinishToolCall stores the output of the running tool call callID.
+func (s *Session) FinishToolCall(callID, output string, failed bool) {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		msg := &s.Messages[i]
+		if msg.Role == types.RoleTool && msg.Tool != nil && msg.Tool.CallID == callID {
+			msg.Content = output
+			msg.Tool.Failed = failed
+			msg.Status = types.StatusComplete
+			return
+		}
+	}
+}
+
+// InterruptToolCalls marks tool calls that are still running as stopped by
+// the user.
+func (s *Session) InterruptToolCalls() {
+	for i := range s.Messages {
+		msg := &s.Messages[i]
+		if msg.Role == types.RoleTool && msg.Status == types.StatusRunning {
+			msg.Content = "Interrupted by the user."
+			if msg.Tool != nil {
+				msg.Tool.Failed = true
+			}
+			msg.Status = types.StatusInterrupted
+		}
+	}
+}
+
+// SetResponseUsage attaches the usage reported for the in-progress response
+// and adds it to the session totals.
+func (s *Session) SetResponseUsage(usage types.TokenUsage) {
+	msg := s.streamingMessage()
+	if msg == nil {
+		return
+	}
+	msg.Usage = &usage
+	s.Usage.Add(usage)
+}
+
+// InterruptResponse keeps the text received so far for the in-progress
+// response and marks it as interrupted. An empty response is removed.
+func (s *Session) InterruptResponse() {
+	msg := s.streamingMessage()
+	if msg == nil {
+		return
+	}
+
+	// Calls that never ran have no results, so they are dropped
+	msg.ToolCalls = nil
+	msg.Content = s.filterSystemReminders(msg.Content)
+	if msg.Content == "" && msg.Reasoning == "" {
+		s.removeResponse()
+		return
+	}
+	msg.Status = types.StatusInterrupted
+}
+
+// streamingMessage returns the in-progress response, or nil if nothing is
+// streaming.
+func (s *Session) streamingMessage() *types.Message {
+	if s.response >= len(s.Messages) || s.Messages[s.response].Status != types.StatusStreaming {
+		return nil
+	}
+	return &s.Messages[s.response]
+}
+
+func (s *Session) removeResponse() {
+	s.Messages = append(s.Messages[:s.response], s.Messages[s.response+1:]...)
 }
 
+// Clear empties the session and gives it a new identity, so that the
+// conversation it held stays saved as it was.
 func (s *Session) Clear() {
-	s.Messages = []string{}
+	now := time.Now()
+	s.ID = newSessionID(now)
+	s.Title = ""
+	s.Created = now
+	s.Updated = now
+	s.Messages = []types.Message{}
+	s.Usage = types.TokenUsage{}
+	s.Params = api.Params{}
 }
 
-func (s *Session) GetMessages() []string {
+func (s *Session) GetMessages() []types.Message {
 	return s.Messages
 }
 
+// LastReplyTruncated reports whether the latest reply was cut off at the
+// token limit, ignoring notices after it.
+func (s *Session) LastReplyTruncated() bool {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		msg := s.Messages[i]
+		if msg.IsNotice() {
+			continue
+		}
+		return msg.Role == types.RoleAssistant && msg.Truncated && msg.Status == types.StatusComplete
+	}
+	return false
+}
+
+// CountUserMessages returns how many turns the user has sent.
+func (s *Session) CountUserMessages() int {
+	count := 0
+	for _, msg := range s.Messages {
+		if msg.Role == types.RoleUser {
+			count++
+		}
+	}
+	return count
+}
+
+// History returns the conversation as provider messages, trimmed so that it
+// fits within budget tokens. Notices, errors and the response still streaming
+// are not part of the history, nor are tool calls without a result.
+func (s *Session) History(budget int) []api.ChatMessage {
+	answered := make(map[string]bool)
+	for _, msg := range s.Messages {
+		if msg.Role == types.RoleTool && msg.Tool != nil && msg.Status != types.StatusRunning {
+			answered[msg.Tool.CallID] = true
+		}
+	}
+
+	var turns []api.ChatMessage
+	for _, msg := range s.Messages {
+		if msg.Status != types.StatusComplete && msg.Status != types.StatusInterrupted {
+			continue
+		}
+
+		turn := api.ChatMessage{Role: string(msg.Role), Content: msg.Content}
+		if msg.ReasoningSignature != "" {
+			turn.Reasoning = msg.Reasoning
+			turn.ReasoningSignature = msg.ReasoningSignature
+		}
+		if msg.Tool != nil {
+			turn.ToolCallID = msg.Tool.CallID
+		}
+		for _, attachment := range msg.Attachments {
+			turn.Parts = append(turn.Parts, attachmentPart(attachment))
+		}
+		for _, call := range msg.ToolCalls {
+			if answered[call.ID] {
+				turn.ToolCalls = append(turn.ToolCalls, api.ToolCall{ID: call.ID, Name: call.Name, Arguments: call.Arguments})
+			}
+		}
+		if turn.Content == "" && len(turn.ToolCalls) == 0 && msg.Role == types.RoleAssistant {
+			continue
+		}
+		turns = append(turns, turn)
+	}
+
+	var system []api.ChatMessage
+	if s.SystemPrompt != "" {
+		system = append(system, api.ChatMessage{Role: api.RoleSystem, Content: s.SystemPrompt})
+	}
+
+	return fitToBudget(system, turns, budget)
+}
+
+// attachmentPart turns an attachment into a content part. Text files are
+// inlined under their name.
+func attachmentPart(attachment types.Attachment) api.ContentPart {
+	if attachment.IsImage() {
+		return api.ContentPart{Type: api.PartImage, MediaType: attachment.MediaType, Data: attachment.Data}
+	}
+	text := fmt.Sprintf("Attached file %s:\n```\n%s\n```", attachment.Name, strings.TrimRight(string(attachment.Data), "\n"))
+	return api.ContentPart{Type: api.PartText, Text: text}
+}
+
 func (s *Session) SetProvider(provider string) {
 	s.CurrentProvider = provider
-	s.Messages = append(s.Messages, "🔄 Switched to "+strings.ToUpper(provider))
+	s.AddNotice("🔄 Switched to " + strings.ToUpper(provider))
 }
 
 func (s *Session) filterSystemReminders(text string) string {
 	re := regexp.MustCompile(`<system-reminder>[\s\S]*?</system-reminder>`)
 	filtered := re.ReplaceAllString(text, "")
 	return strings.TrimSpace(filtered)
-}
\ No newline at end of file
+}
@@ -0,0 +1,28 @@
+package chat
+
+import (
+	"testing"
+
+	"Chat2/internal/types"
+)
+
+func TestToolMessagesWithoutCalls(t *testing.T) {
+	s := NewSession("openai")
+	s.AddUserMessage("List the files")
+	s.AddMessage(types.Message{Role: types.RoleTool, Content: "stray", Status: types.StatusRunning})
+	s.BeginToolCall(types.ToolCall{ID: "call_1", Name: "list_files"})
+
+	s.AppendToolOutput("call_1", "go.mod\n")
+	s.AppendToolOutput("missing", "ignored")
+	s.InterruptToolCalls()
+	s.FinishToolCall("call_1", "go.mod\nmain.go\n", false)
+
+	last := s.Messages[len(s.Messages)-1]
+	if last.Content != "go.mod\nmain.go\n" || last.Status != types.StatusComplete || last.Tool.Failed {
+		t.Errorf("tool message = %+v", last)
+	}
+	if stray := s.Messages[1]; stray.Status != types.StatusInterrupted {
+		t.Errorf("stray tool message status = %v, want interrupted", stray.Status)
+	}
+	s.History(1000)
+}
@@ -0,0 +1,293 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"Chat2/internal/api"
+	"Chat2/internal/types"
+)
+
+// sessionExt is the extension of session files.
+const sessionExt = ".jsonl"
+
+// maxPreviewRunes caps the first prompt kept in a session's header.
+const maxPreviewRunes = 80
+
+// SessionInfo describes a saved session without its messages.
+type SessionInfo struct {
+	ID       string    `json:"id"`
+	Title    string    `json:"title,omitempty"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+	Provider string    `json:"provider,omitempty"`
+	Model    string    `json:"model,omitempty"`
+	// Messages counts the saved messages; Preview is the start of the
+	// first prompt.
+	Messages int    `json:"messages"`
+	Preview  string `json:"preview,omitempty"`
+}
+
+// sessionHeader is the first line of a session file. Every following line
+// holds one message.
+type sessionHeader struct {
+	SessionInfo
+	Usage  types.TokenUsage `json:"usage"`
+	Params api.Params       `json:"params"`
+}
+
+// Store keeps sessions in a directory as JSONL files, one per session. A
+// file is replaced as a whole on every save, so that a crash while writing
+// leaves the previous version in place.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store for the sessions in dir, which is created on
+// the first save.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+func (st *Store) path(id string) string {
+	return filepath.Join(st.dir, id+sessionExt)
+}
+
+// Save writes the conversation of s. Notices and replies still in progress
+// are not saved, and a session without a prompt is not saved at all.
+func (st *Store) Save(s *Session) error {
+	var messages []types.Message
+	for _, msg := range s.Messages {
+		if msg.IsNotice() || msg.Status == types.StatusStreaming || msg.Status == types.StatusRunning {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	if s.CountUserMessages() == 0 {
+		return nil
+	}
+
+	s.Updated = time.Now()
+	return st.save(s, messages)
+}
+
+// save writes messages as the conversation of s.
+func (st *Store) save(s *Session, messages []types.Message) error {
+	header := sessionHeader{
+		SessionInfo: SessionInfo{
+			ID:       s.ID,
+			Title:    s.Title,
+			Created:  s.Created,
+			Updated:  s.Updated,
+			Provider: s.CurrentProvider,
+			Messages: len(messages),
+		},
+		Usage:  s.Usage,
+		Params: s.Params,
+	}
+	for _, msg := range messages {
+		if msg.Role == types.RoleUser && header.Preview == "" {
+			header.Preview = preview(msg.Content)
+		}
+		if msg.Role == types.RoleAssistant && msg.Provider != "" {
+			header.Provider, header.Model = msg.Provider, msg.Model
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	if err := encoder.Encode(header); err != nil {
+		return err
+	}
+	for _, msg := range messages {
+		if err := encoder.Encode(msg); err != nil {
+			return err
+		}
+	}
+	return st.write(s.ID, buf.Bytes())
+}
+
+// write replaces the file of session id with data by writing a temporary
+// file next to it and renaming it over the old one.
+func (st *Store) write(id string, data []byte) error {
+	if err := os.MkdirAll(st.dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(st.dir, id+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), st.path(id))
+}
+
+// Load reads the session with the given ID.
+func (st *Store) Load(id string) (*Session, error) {
+	file, err := os.Open(st.path(id))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	// Lines hold whole messages, attachments included
+	scanner.Buffer(nil, 64*1024*1024)
+
+	var header sessionHeader
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("session %s is empty", id)
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("session %s: %w", id, err)
+	}
+
+	s := NewSession(header.Provider)
+	s.ID = header.ID
+	s.Title = header.Title
+	s.Created = header.Created
+	s.Updated = header.Updated
+	s.Usage = header.Usage
+	s.Params = header.Params
+	for scanner.Scan() {
+		var msg types.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			return nil, fmt.Errorf("session %s: %w", id, err)
+		}
+		// A tool result that does not name its call cannot be sent back
+		if msg.Role == types.RoleTool && msg.Tool == nil {
+			continue
+		}
+		s.Messages = append(s.Messages, msg)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Rename changes the title of a saved session.
+func (st *Store) Rename(id, title string) error {
+	s, err := st.Load(id)
+	if err != nil {
+		return err
+	}
+	s.Title = title
+	return st.save(s, s.Messages)
+}
+
+// Duplicate saves a copy of a session under a new ID and returns it.
+func (st *Store) Duplicate(id string) (*Session, error) {
+	s, err := st.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	s.Created = time.Now()
+	s.Updated = s.Created
+	s.ID = newSessionID(s.Created)
+	if s.Title != "" {
+		s.Title += " (copy)"
+	}
+	return s, st.save(s, s.Messages)
+}
+
+// Delete removes a saved session.
+func (st *Store) Delete(id string) error {
+	return os.Remove(st.path(id))
+}
+
+// Latest loads the most recently updated session, or returns nil when none
+// is saved.
+func (st *Store) Latest() (*Session, error) {
+	sessions, err := st.List()
+	if err != nil || len(sessions) == 0 {
+		return nil, err
+	}
+	return st.Load(sessions[0].ID)
+}
+
+// List returns the saved sessions, most recently updated first. Files that
+// cannot be read are skipped.
+func (st *Store) List() ([]SessionInfo, error) {
+	entries, err := os.ReadDir(st.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []SessionInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), sessionExt) {
+			continue
+		}
+		if info, err := st.readInfo(entry.Name()); err == nil {
+			sessions = append(sessions, info)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Updated.After(sessions[j].Updated)
+	})
+	return sessions, nil
+}
+
+// readInfo reads only the header line of a session file.
+func (st *Store) readInfo(name string) (SessionInfo, error) {
+	file, err := os.Open(filepath.Join(st.dir, name))
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	defer file.Close()
+
+	line, err := bufio.NewReader(file).ReadBytes('\n')
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	var header sessionHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return SessionInfo{}, err
+	}
+	return header.SessionInfo, nil
+}
+
+// newSessionID returns a unique ID that sorts by creation time, such as
+// "20250102-150405-a1b2c3".
+func newSessionID(created time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+	return created.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// preview returns the first line of text, shortened to maxPreviewRunes.
+func preview(text string) string {
+	text, _, _ = strings.Cut(strings.TrimSpace(text), "\n")
+	if runes := []rune(text); len(runes) > maxPreviewRunes {
+		return string(runes[:maxPreviewRunes-1]) + "…"
+	}
+	return text
+}
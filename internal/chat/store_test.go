@@ -0,0 +1,233 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"Chat2/internal/types"
+)
+
+// newTestSession returns a session with one exchange.
+func newTestSession(prompt string) *Session {
+	s := NewSession("openai")
+	s.AddUserMessage(prompt, types.Attachment{Name: "notes.txt", MediaType: "text/plain", Data: []byte("notes")})
+	s.AddAIResponse("Hello", "anthropic", "claude-test")
+	return s
+}
+
+// messagesJSON encodes messages, which compares them without the monotonic
+// clock readings that do not survive a save.
+func messagesJSON(t *testing.T, messages []types.Message) string {
+	t.Helper()
+	data, err := json.Marshal(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	st := NewStore(t.TempDir())
+	s := newTestSession("What is Go?\nIn one line.")
+	s.Title = "Go"
+	s.Usage = types.TokenUsage{PromptTokens: 10, CompletionTokens: 5, Cost: 0.01, Priced: true}
+	if err := s.Params.Set("temperature", "0.5"); err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(types.Message{
+		Role:      types.RoleAssistant,
+		Content:   "Running",
+		Status:    types.StatusInterrupted,
+		ToolCalls: []types.ToolCall{{ID: "call_1", Name: "read_file", Arguments: `{"path":"go.mod"}`}},
+		Reasoning: "Look at go.mod",
+	})
+	s.AddMessage(types.Message{Role: types.RoleTool, Content: "module x", Tool: &types.ToolResult{CallID: "call_1", Name: "read_file"}})
+	want := append([]types.Message(nil), s.Messages...)
+	s.AddNotice("Saved")
+	s.BeginResponse("anthropic", "claude-test")
+
+	if err := st.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := st.Load(s.ID)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if loaded.ID != s.ID || loaded.Title != "Go" || loaded.CurrentProvider != "anthropic" {
+		t.Errorf("loaded %q titled %q on %q", loaded.ID, loaded.Title, loaded.CurrentProvider)
+	}
+	if !loaded.Created.Equal(s.Created) || !loaded.Updated.Equal(s.Updated) {
+		t.Errorf("times = %v, %v, want %v, %v", loaded.Created, loaded.Updated, s.Created, s.Updated)
+	}
+	if loaded.Usage != s.Usage || loaded.Params.Get("temperature") != "0.5" {
+		t.Errorf("usage = %+v, params = %+v", loaded.Usage, loaded.Params)
+	}
+	if got, want := messagesJSON(t, loaded.Messages), messagesJSON(t, want); got != want {
+		t.Errorf("messages = %s\nwant %s", got, want)
+	}
+
+	sessions, err := st.List()
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("List = %v, %v, want one session", sessions, err)
+	}
+	info := sessions[0]
+	if info.Messages != 4 || info.Preview != "What is Go?" || info.Model != "claude-test" {
+		t.Errorf("info = %+v", info)
+	}
+}
+
+func TestStoreSkipsSessionsWithoutPrompts(t *testing.T) {
+	st := NewStore(filepath.Join(t.TempDir(), "sessions"))
+	s := NewSession("openai")
+	s.AddNotice("Welcome")
+	if err := st.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if sessions, err := st.List(); err != nil || len(sessions) != 0 {
+		t.Errorf("List = %v, %v, want no sessions", sessions, err)
+	}
+}
+
+func TestStoreLoadsLegacyFiles(t *testing.T) {
+	dir := t.TempDir()
+	lines := []string{
+		`{"id":"old","created":"2024-01-02T15:04:05Z","updated":"2024-01-02T15:04:05Z","provider":"openai","messages":3}`,
+		`{"role":"user","content":"Hi","timestamp":"2024-01-02T15:04:05Z","status":0}`,
+		`{"role":"assistant","content":"Hel","timestamp":"2024-01-02T15:04:06Z","status":2}`,
+		`{"role":"system","content":"Rate limited","timestamp":"2024-01-02T15:04:07Z","status":5,"error":{"kind":"rate_limit","retry_after":20000000000}}`,
+		`{"role":"tool","content":"orphaned output","timestamp":"2024-01-02T15:04:08Z","status":0}`,
+	}
+	if err := os.WriteFile(filepath.Join(dir, "old.jsonl"), []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewStore(dir).Load("old")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(s.Messages) != 3 {
+		t.Fatalf("loaded %d messages, want 3", len(s.Messages))
+	}
+	for i, want := range []types.MessageStatus{types.StatusComplete, types.StatusInterrupted, types.StatusError} {
+		if got := s.Messages[i].Status; got != want {
+			t.Errorf("message %d status = %v, want %v", i, got, want)
+		}
+	}
+	if detail := s.Messages[2].Error; detail == nil || detail.RetryAfter != 20*time.Second {
+		t.Errorf("error = %+v, want a retry after 20s", detail)
+	}
+}
+
+func TestMessageJSON(t *testing.T) {
+	msg := types.Message{
+		Role:   types.RoleSystem,
+		Status: types.StatusError,
+		Error:  &types.ErrorDetail{Kind: "rate_limit", RetryAfter: 1500 * time.Millisecond, Hint: "Wait"},
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"status":"error"`, `"retry_after":"1.5s"`, `"hint":"Wait"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s does not contain %s", data, want)
+		}
+	}
+
+	var decoded types.Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Status != msg.Status || *decoded.Error != *msg.Error {
+		t.Errorf("decoded %+v with %+v, want %+v", decoded, decoded.Error, msg.Error)
+	}
+
+	for _, bad := range []string{`{"status":"lost"}`, `{"error":{"retry_after":"soon"}}`} {
+		if err := json.Unmarshal([]byte(bad), &decoded); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestStoreRenameDuplicateDelete(t *testing.T) {
+	st := NewStore(t.TempDir())
+	s := newTestSession("Hi")
+	s.Title = "Greeting"
+	if err := st.Save(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.Rename(s.ID, "Hello there"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	renamed, err := st.Load(s.ID)
+	if err != nil || renamed.Title != "Hello there" || len(renamed.Messages) != 2 {
+		t.Fatalf("after Rename: %+v, %v", renamed, err)
+	}
+
+	copied, err := st.Duplicate(s.ID)
+	if err != nil {
+		t.Fatalf("Duplicate: %v", err)
+	}
+	if copied.ID == s.ID || copied.Title != "Hello there (copy)" {
+		t.Errorf("copy %q titled %q", copied.ID, copied.Title)
+	}
+	loaded, err := st.Load(copied.ID)
+	if err != nil || messagesJSON(t, loaded.Messages) != messagesJSON(t, renamed.Messages) {
+		t.Errorf("copy holds %+v, %v, want the original's messages", loaded, err)
+	}
+
+	if err := st.Delete(s.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := st.Load(s.ID); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load after Delete = %v, want ErrNotExist", err)
+	}
+	if sessions, _ := st.List(); len(sessions) != 1 || sessions[0].ID != copied.ID {
+		t.Errorf("List = %+v, want only the copy", sessions)
+	}
+}
+
+func TestStoreLatest(t *testing.T) {
+	dir := t.TempDir()
+	st := NewStore(dir)
+	if s, err := st.Latest(); s != nil || err != nil {
+		t.Errorf("Latest of an empty store = %v, %v", s, err)
+	}
+
+	base := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	for i, offset := range []time.Duration{time.Hour, 3 * time.Hour, 2 * time.Hour} {
+		s := newTestSession("Question")
+		s.ID = []string{"first", "newest", "middle"}[i]
+		s.Updated = base.Add(offset)
+		if err := st.save(s, s.Messages); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.jsonl"), []byte("not json\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := st.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var order []string
+	for _, info := range sessions {
+		order = append(order, info.ID)
+	}
+	if strings.Join(order, ",") != "newest,middle,first" {
+		t.Errorf("List order = %v, want newest, middle, first", order)
+	}
+
+	latest, err := st.Latest()
+	if err != nil || latest == nil || latest.ID != "newest" {
+		t.Errorf("Latest = %+v, %v, want newest", latest, err)
+	}
+}
@@ -36,10 +36,16 @@ func NewRegistry(model types.UIModel) *Registry {
 
 func (r *Registry) registerDefaultCommands() {
 	r.Register("help", "show help", &HelpCommand{model: r.model})
-	r.Register("sessions", "list sessions", &SessionsCommand{model: r.model})
+	r.Register("sessions", "browse, resume and manage saved sessions", &SessionsCommand{model: r.model})
 	r.Register("new", "start a new session", &NewSessionCommand{model: r.model})
-	r.Register("model", "switch model", &SwitchModelCommand{model: r.model})
+	r.Register("title", "show or rename the session: /title <text>", &TitleCommand{model: r.model})
+	r.Register("model", "pick a model, or /model <id> to switch directly", &SwitchModelCommand{model: r.model})
 	r.Register("theme", "switch theme", &ThemeCommand{model: r.model})
+	r.Register("agent", "toggle agent mode, or /agent on|off", &AgentCommand{model: r.model})
+	r.Register("attach", "attach a file or image to the next message", &AttachCommand{model: r.model})
+	r.Register("json", "reply in JSON matching a schema: /json <schema-file> or /json off", &JSONCommand{model: r.model})
+	r.Register("compare", "send each prompt to several models: /compare <target> <target> … or /compare off", &CompareCommand{model: r.model})
+	r.Register("params", "show or set generation parameters: /params name=value …", &ParamsCommand{model: r.model})
 	r.Register("share", "shares the current session", &ShareCommand{model: r.model})
 	r.Register("p_drive", "open drive to see folders", &DriveCommand{model: r.model})
 	r.Register("exit", "exit the app", &ExitCommand{})
@@ -60,7 +66,7 @@ func (r *Registry) Execute(input string) (tea.Model, tea.Cmd) {
 
 	parts := strings.Fields(input[1:]) // Remove "/" and split
 	if len(parts) == 0 {
-		r.model.AddMessage("❌ Empty command")
+		r.model.AddMessage(types.NewError("Empty command"))
 		return r.model, nil
 	}
 
@@ -71,7 +77,7 @@ func (r *Registry) Execute(input string) (tea.Model, tea.Cmd) {
 		return cmd.Handler.Execute(args)
 	}
 
-	r.model.AddMessage("❌ Unknown command: /" + cmdName)
+	r.model.AddMessage(types.NewError("Unknown command: /" + cmdName))
 	return r.model, nil
 }
 
@@ -91,21 +97,42 @@ func (c *HelpCommand) Execute(args []string) (tea.Model, tea.Cmd) {
 	helpText := "Available Commands:\n"
 	// This would need access to registry, simplified for now
 	helpText += "  /help - show help\n"
-	helpText += "  /sessions - list sessions\n"
+	helpText += "  /sessions - browse and resume saved sessions\n"
 	helpText += "  /new - start a new session\n"
-	helpText += "  /model - switch model\n"
+	helpText += "  /title [text] - show or rename the session\n"
+	helpText += "  /model [id] - pick or switch model\n"
+	helpText += "  /agent [on|off] - toggle agent mode\n"
+	helpText += "  /attach <path> - attach a file or image\n"
+	helpText += "  /json <schema-file|off> - structured JSON replies\n"
+	helpText += "  /params [name=value|reset] - generation parameters\n"
+	helpText += "  /compare <targets…|off> - compare 2-4 models side by side\n"
 	helpText += "  /theme - switch theme\n"
 	helpText += "  /share - shares the current session\n"
 	helpText += "  /p_drive - open drive to see folders\n"
 	helpText += "  /exit - exit the app\n"
-	c.model.AddMessage(helpText)
+	c.model.AddMessage(types.NewNotice(helpText))
 	return c.model, nil
 }
 
 type SessionsCommand struct{ model types.UIModel }
 
 func (c *SessionsCommand) Execute(args []string) (tea.Model, tea.Cmd) {
-	c.model.AddMessage("📋 No saved sessions found.")
+	return c.model, c.model.OpenSessionBrowser()
+}
+
+type TitleCommand struct{ model types.UIModel }
+
+func (c *TitleCommand) Execute(args []string) (tea.Model, tea.Cmd) {
+	if len(args) == 0 {
+		if title := c.model.SessionTitle(); title != "" {
+			c.model.AddMessage(types.NewNotice("🏷️ " + title + "\nRename it with /title <text>."))
+		} else {
+			c.model.AddMessage(types.NewNotice("This session has no title yet. Name it with /title <text>."))
+		}
+		return c.model, nil
+	}
+	c.model.SetSessionTitle(strings.Join(args, " "))
+	c.model.AddMessage(types.NewSuccess("🏷️ Session renamed to " + c.model.SessionTitle()))
 	return c.model, nil
 }
 
@@ -113,19 +140,108 @@ type NewSessionCommand struct{ model types.UIModel }
 
 func (c *NewSessionCommand) Execute(args []string) (tea.Model, tea.Cmd) {
 	c.model.ClearMessages()
-	c.model.AddMessage("🎉 Started new session!")
+	c.model.AddMessage(types.NewSuccess("🎉 Started new session!"))
 	return c.model, nil
 }
 
 type SwitchModelCommand struct{ model types.UIModel }
 
 func (c *SwitchModelCommand) Execute(args []string) (tea.Model, tea.Cmd) {
-	providers := c.model.GetAvailableProviders()
-	if len(providers) > 1 {
-		return c.model, c.model.SwitchProvider()
+	if len(c.model.GetAvailableProviders()) == 0 {
+		c.model.AddMessage(types.NewError("No AI provider configured. Please set up API keys."))
+		return c.model, nil
+	}
+	if len(args) > 0 {
+		c.model.SelectModel(strings.Join(args, " "))
+		return c.model, nil
+	}
+	return c.model, c.model.OpenModelPicker()
+}
+
+type AgentCommand struct{ model types.UIModel }
+
+func (c *AgentCommand) Execute(args []string) (tea.Model, tea.Cmd) {
+	enable := !c.model.AgentMode()
+	if len(args) > 0 {
+		switch strings.ToLower(args[0]) {
+		case "on":
+			enable = true
+		case "off":
+			enable = false
+		default:
+			c.model.AddMessage(types.NewError("Usage: /agent [on|off]"))
+			return c.model, nil
+		}
+	}
+
+	c.model.SetAgentMode(enable)
+	if enable {
+		c.model.AddMessage(types.NewSuccess("🤖 Agent mode on. The assistant works through tool calls until the task is done; type to steer it between steps, Esc to stop."))
+	} else {
+		c.model.AddMessage(types.NewSuccess("💬 Agent mode off."))
+	}
+	return c.model, nil
+}
+
+type AttachCommand struct{ model types.UIModel }
+
+func (c *AttachCommand) Execute(args []string) (tea.Model, tea.Cmd) {
+	if len(args) == 0 {
+		c.model.AddMessage(types.NewError("Usage: /attach <path>, or pick a file with /p_drive"))
+		return c.model, nil
+	}
+	c.model.AttachFile(strings.Join(args, " "))
+	return c.model, nil
+}
+
+type JSONCommand struct{ model types.UIModel }
+
+func (c *JSONCommand) Execute(args []string) (tea.Model, tea.Cmd) {
+	if len(args) == 0 {
+		c.model.AddMessage(types.NewError("Usage: /json <schema-file>, or /json off"))
+		return c.model, nil
+	}
+	if len(args) == 1 && strings.ToLower(args[0]) == "off" {
+		c.model.ClearJSONSchema()
+		return c.model, nil
+	}
+	c.model.SetJSONSchema(strings.Join(args, " "))
+	return c.model, nil
+}
+
+type ParamsCommand struct{ model types.UIModel }
+
+func (c *ParamsCommand) Execute(args []string) (tea.Model, tea.Cmd) {
+	if len(args) == 1 && strings.ToLower(args[0]) == "reset" {
+		c.model.ResetParams()
+		c.model.AddMessage(types.NewSuccess("⚙️ Session parameters cleared; the configured values apply again."))
+		return c.model, nil
+	}
+
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			c.model.AddMessage(types.NewError("Usage: /params [name=value ...], or /params reset"))
+			return c.model, nil
+		}
+		if err := c.model.SetParam(strings.ToLower(name), value); err != nil {
+			c.model.AddMessage(types.NewError(err.Error()))
+			return c.model, nil
+		}
+	}
+
+	c.model.AddMessage(types.NewNotice(c.model.DescribeParams()))
+	return c.model, nil
+}
+
+type CompareCommand struct{ model types.UIModel }
+
+func (c *CompareCommand) Execute(args []string) (tea.Model, tea.Cmd) {
+	if len(args) == 1 && strings.ToLower(args[0]) == "off" {
+		c.model.ClearCompareTargets()
+		return c.model, nil
 	}
-	currentProvider := c.model.GetCurrentProvider()
-	c.model.AddMessage("Only one provider available: " + currentProvider)
+	c.model.SetCompareTargets(args)
 	return c.model, nil
 }
 
@@ -149,7 +265,7 @@ func (c *ThemeCommand) Execute(args []string) (tea.Model, tea.Cmd) {
 	if themes.SetTheme(nextTheme) {
 		c.model.SetCurrentTheme(nextTheme)
 		themePreview := themes.GetThemePreview(nextTheme)
-		c.model.AddMessage(fmt.Sprintf("🎨 Switched to %s", themePreview))
+		c.model.AddMessage(types.NewSuccess(fmt.Sprintf("🎨 Switched to %s", themePreview)))
 	}
 
 	return c.model, nil
@@ -158,7 +274,7 @@ func (c *ThemeCommand) Execute(args []string) (tea.Model, tea.Cmd) {
 type ShareCommand struct{ model types.UIModel }
 
 func (c *ShareCommand) Execute(args []string) (tea.Model, tea.Cmd) {
-	c.model.AddMessage("🔗 Session sharing not implemented yet.")
+	c.model.AddMessage(types.NewNotice("🔗 Session sharing is not available yet."))
 	return c.model, nil
 }
 
@@ -170,7 +286,7 @@ func (c *DriveCommand) Execute(args []string) (tea.Model, tea.Cmd) {
 	
 	cwd, err := os.Getwd()
 	if err != nil {
-		c.model.AddMessage("❌ Error accessing current directory: " + err.Error())
+		c.model.AddMessage(types.NewError("Error accessing current directory: " + err.Error()))
 		return c.model, nil
 	}
 	c.model.SetFileBrowserPath(cwd)
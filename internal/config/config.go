@@ -2,34 +2,200 @@ package config
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 )
 
-func LoadAPIKeys() map[string]string {
+// DefaultContextTokens is the approximate token budget for the history sent
+// with each request when none is configured.
+const DefaultContextTokens = 8000
+
+// ProviderConfig declares an OpenAI-compatible endpoint such as vLLM,
+// LM Studio, Together, Groq or an internal gateway.
+type ProviderConfig struct {
+	Name    string `json:"name"`
+	BaseURL string `json:"base_url"`
+	// APIKeyEnv names the environment variable holding the API key. Leave
+	// it empty for endpoints that need no key.
+	APIKeyEnv string `json:"api_key_env"`
+	Model     string `json:"model"`
+	// Headers are sent with every request. Values may reference
+	// environment variables as $VAR or ${VAR}.
+	Headers map[string]string `json:"headers"`
+	// Vision marks endpoints whose models accept images.
+	Vision bool `json:"vision"`
+	// Tools and JSONSchema can be set to false for endpoints that reject
+	// tool definitions or a response_format schema. Both default to true.
+	Tools      *bool `json:"tools,omitempty"`
+	JSONSchema *bool `json:"json_schema,omitempty"`
+}
+
+// Price is a model price in US dollars per million tokens.
+// api.PriceFromConfig converts it for cost accounting.
+type Price struct {
+	Input       float64 `json:"input"`
+	Output      float64 `json:"output"`
+	CachedInput float64 `json:"cached_input"`
+}
+
+// Config holds the settings read from the config file.
+type Config struct {
+	ContextTokens int              `json:"context_tokens"`
+	Providers     []ProviderConfig `json:"providers"`
+	// Prices adds or overrides model prices used for cost accounting.
+	Prices map[string]Price `json:"prices"`
+	// Fallbacks lists "provider/model" targets, in order, to try when the
+	// current provider fails before it starts replying.
+	Fallbacks []string    `json:"fallbacks"`
+	Shell     ShellConfig `json:"shell"`
+	Agent     AgentConfig `json:"agent"`
+	// Params are the generation settings of every request, and
+	// ProviderParams override them per provider ID.
+	Params         GenerationParams            `json:"params"`
+	ProviderParams map[string]GenerationParams `json:"provider_params"`
+	// TitleModel is the "provider/model" target that names sessions. The
+	// current model does when it is empty.
+	TitleModel string `json:"title_model"`
+}
+
+// GenerationParams are sampling settings. Unset fields keep the provider's
+// defaults. api.ParamsFromConfig converts them for requests.
+type GenerationParams struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+	MaxTokens       int      `json:"max_tokens,omitempty"`
+	Stop            []string `json:"stop,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"`
+}
+
+// AgentConfig bounds how long agent mode works on a task before it stops.
+type AgentConfig struct {
+	// MaxSteps caps the model rounds per task.
+	MaxSteps int `json:"max_steps"`
+	// MaxTokens caps the prompt and completion tokens spent per task.
+	MaxTokens int `json:"max_tokens"`
+}
+
+// ShellConfig controls the command tool. Commands starting with an Allow
+// entry run without asking, commands starting with a Deny entry are refused,
+// and all others need the user's approval.
+type ShellConfig struct {
+	Allow          []string `json:"allow"`
+	Deny           []string `json:"deny"`
+	TimeoutSeconds int      `json:"timeout_seconds"`
+	MaxOutputBytes int      `json:"max_output_bytes"`
+}
+
+// Dir returns the directory holding PUKU's config file, following the XDG
+// base directory spec.
+func Dir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "puku")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", "puku")
+	}
+	return ".puku"
+}
+
+// Path returns the config file location, which PUKU_CONFIG overrides.
+func Path() string {
+	if path := os.Getenv("PUKU_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(Dir(), "config.json")
+}
+
+// Load reads the config file. A missing file is not an error. On error the
+// returned config still holds usable defaults, and the error lists every
+// problem found.
+func Load() (*Config, error) {
+	cfg := &Config{}
+	var errs []error
+
+	if data, err := os.ReadFile(Path()); err == nil {
+		if err := json.Unmarshal(data, cfg); err != nil {
+			cfg = &Config{}
+			errs = append(errs, fmt.Errorf("invalid config file %s: %w", Path(), err))
+		}
+	} else if !os.IsNotExist(err) {
+		errs = append(errs, fmt.Errorf("reading config file: %w", err))
+	}
+
+	var providers []ProviderConfig
+	for i, provider := range cfg.Providers {
+		if provider.Name == "" || provider.BaseURL == "" {
+			errs = append(errs, fmt.Errorf("provider %d in %s needs a name and base_url", i+1, Path()))
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	cfg.Providers = providers
+
+	cfg.ContextTokens = loadContextBudget(cfg.ContextTokens)
+	return cfg, errors.Join(errs...)
+}
+
+// LoadAPIKeys reads API keys for the given providers. keyEnvs maps each
+// provider ID to the environment variable holding its key; values in the
+// .env file take precedence over the environment.
+func LoadAPIKeys(keyEnvs map[string]string) map[string]string {
 	keys := make(map[string]string)
+	dotEnv := readDotEnv()
 
-	if openrouterKey := os.Getenv("OPENROUTER_API_KEY"); openrouterKey != "" {
-		keys["openrouter"] = openrouterKey
-	}
-
-	if file, err := os.Open(".env"); err == nil {
-		defer file.Close()
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			line := strings.TrimSpace(scanner.Text())
-			if strings.Contains(line, "=") && !strings.HasPrefix(line, "#") {
-				parts := strings.SplitN(line, "=", 2)
-				key := strings.TrimSpace(parts[0])
-				value := strings.TrimSpace(parts[1])
-
-				switch key {
-				case "OPENROUTER_API_KEY":
-					keys["openrouter"] = value
-				}
-			}
+	for provider, envVar := range keyEnvs {
+		if value := os.Getenv(envVar); value != "" {
+			keys[provider] = value
+		}
+		if value := dotEnv[envVar]; value != "" {
+			keys[provider] = value
 		}
 	}
 
 	return keys
 }
+
+// loadContextBudget returns the token budget for conversation history.
+// PUKU_CONTEXT_TOKENS in the environment or the .env file overrides the
+// configured value.
+func loadContextBudget(configured int) int {
+	value := os.Getenv("PUKU_CONTEXT_TOKENS")
+	if envValue, ok := readDotEnv()["PUKU_CONTEXT_TOKENS"]; ok {
+		value = envValue
+	}
+
+	if budget, err := strconv.Atoi(value); err == nil && budget > 0 {
+		return budget
+	}
+	if configured > 0 {
+		return configured
+	}
+	return DefaultContextTokens
+}
+
+func readDotEnv() map[string]string {
+	values := make(map[string]string)
+
+	file, err := os.Open(".env")
+	if err != nil {
+		return values
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.Contains(line, "=") && !strings.HasPrefix(line, "#") {
+			parts := strings.SplitN(line, "=", 2)
+			values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	return values
+}
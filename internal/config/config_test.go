@@ -0,0 +1,34 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadReportsEveryInvalidProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("PUKU_CONFIG", path)
+	data := `{"providers": [
+		{"name": "groq"},
+		{"name": "vllm", "base_url": "http://localhost:8000/v1"},
+		{"base_url": "http://localhost:1234/v1"}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load()
+	if err == nil {
+		t.Fatal("Load succeeded, want errors for providers 1 and 3")
+	}
+	for _, want := range []string{"provider 1 in", "provider 3 in"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+	if len(cfg.Providers) != 1 || cfg.Providers[0].Name != "vllm" {
+		t.Errorf("providers = %+v, want only vllm", cfg.Providers)
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// CacheDir returns the directory for cached data such as model listings,
+// following the XDG base directory spec.
+func CacheDir() string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "puku")
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "puku")
+	}
+	return filepath.Join(".puku", "cache")
+}
+
+// DataDir returns the directory for data kept across runs, such as saved
+// sessions, following the XDG base directory spec.
+func DataDir() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "puku")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "share", "puku")
+	}
+	return filepath.Join(".puku", "data")
+}
+
+func modelChoicesPath() string {
+	return filepath.Join(Dir(), "models.json")
+}
+
+// LoadModelChoices returns the model last picked for each provider.
+func LoadModelChoices() map[string]string {
+	choices := make(map[string]string)
+	if data, err := os.ReadFile(modelChoicesPath()); err == nil {
+		json.Unmarshal(data, &choices)
+	}
+	return choices
+}
+
+// SaveModelChoice remembers model as the choice for provider.
+func SaveModelChoice(provider, model string) error {
+	choices := LoadModelChoices()
+	choices[provider] = model
+
+	data, err := json.MarshalIndent(choices, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(Dir(), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(modelChoicesPath(), data, 0o644)
+}
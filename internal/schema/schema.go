@@ -0,0 +1,426 @@
+// Package schema validates JSON values against a JSON schema. It covers the
+// keywords structured output schemas use in practice: type, enum, const,
+// properties, required, additionalProperties, items, prefixItems, the
+// length, size and range limits, pattern, allOf, anyOf, oneOf, not and
+// local $ref pointers. Other keywords, such as format, are ignored.
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxProblems caps how many problems a validation reports.
+	maxProblems = 10
+	// maxNameLength is the longest schema name providers accept.
+	maxNameLength = 64
+)
+
+// Schema is a parsed JSON schema.
+type Schema struct {
+	// Name identifies the schema to providers, derived from its file name.
+	Name string
+	// Raw is the schema document as read.
+	Raw  json.RawMessage
+	root interface{}
+}
+
+// Load reads and parses the schema file at path.
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	s.Name = schemaName(path)
+	return s, nil
+}
+
+// Parse parses a schema document. The root must be a JSON object, as
+// providers take no other response schema; nested schemas may also be true
+// or false.
+func Parse(data []byte) (*Schema, error) {
+	var root interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, ok := root.(map[string]interface{}); !ok {
+		return nil, errors.New("a schema must be a JSON object")
+	}
+	return &Schema{Name: "response", Raw: json.RawMessage(data), root: root}, nil
+}
+
+// schemaName turns a file name like "person.schema.json" into "person".
+// Providers only accept up to 64 letters, digits, underscores and dashes.
+func schemaName(path string) string {
+	name, _, _ := strings.Cut(filepath.Base(path), ".")
+	name = regexp.MustCompile(`[^a-zA-Z0-9_-]+`).ReplaceAllString(name, "_")
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+	if name == "" {
+		return "response"
+	}
+	return name
+}
+
+// ValidationError lists why a value does not match a schema.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// ValidateJSON checks that data is a JSON value matching the schema.
+func (s *Schema) ValidateJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return &ValidationError{Problems: []string{"not valid JSON: " + err.Error()}}
+	}
+	return s.Validate(value)
+}
+
+// Validate checks a decoded JSON value against the schema.
+func (s *Schema) Validate(value interface{}) error {
+	v := validator{root: s.root}
+	v.check(s.root, value, "$")
+	if len(v.problems) == 0 {
+		return nil
+	}
+	if len(v.problems) > maxProblems {
+		v.problems = append(v.problems[:maxProblems], fmt.Sprintf("and %d more problems", len(v.problems)-maxProblems))
+	}
+	return &ValidationError{Problems: v.problems}
+}
+
+type validator struct {
+	root     interface{}
+	problems []string
+	// depth guards against $ref cycles
+	depth int
+}
+
+func (v *validator) fail(path, format string, args ...interface{}) {
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// matches reports whether value matches schema without recording problems.
+func (v *validator) matches(schema, value interface{}, path string) bool {
+	probe := validator{root: v.root, depth: v.depth}
+	probe.check(schema, value, path)
+	return len(probe.problems) == 0
+}
+
+func (v *validator) check(schema, value interface{}, path string) {
+	switch schema := schema.(type) {
+	case bool:
+		if !schema {
+			v.fail(path, "no value is allowed here")
+		}
+		return
+	case map[string]interface{}:
+		v.checkObjectSchema(schema, value, path)
+	}
+}
+
+func (v *validator) checkObjectSchema(schema map[string]interface{}, value interface{}, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		target, err := v.resolve(ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		if v.depth > 32 {
+			v.fail(path, "$ref %s nests too deeply", ref)
+			return
+		}
+		v.depth++
+		v.check(target, value, path)
+		v.depth--
+	}
+
+	if types, ok := schema["type"]; ok && !matchesType(types, value) {
+		v.fail(path, "expected %s, got %s", describeType(types), typeOf(value))
+		return
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !containsValue(enum, value) {
+		v.fail(path, "must be one of %s", compact(enum))
+	}
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(constant, value) {
+		v.fail(path, "must be %s", compact(constant))
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		v.checkObject(schema, value, path)
+	case []interface{}:
+		v.checkArray(schema, value, path)
+	case string:
+		v.checkString(schema, value, path)
+	case float64:
+		v.checkNumber(schema, value, path)
+	}
+
+	v.checkCombinators(schema, value, path)
+}
+
+func (v *validator) checkObject(schema map[string]interface{}, object map[string]interface{}, path string) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := object[name]; !present {
+					v.fail(path, "missing required property %q", name)
+				}
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		childPath := path + "." + name
+		if property, ok := properties[name]; ok {
+			v.check(property, object[name], childPath)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(path, "property %q is not allowed", name)
+			}
+		case map[string]interface{}:
+			v.check(additional, object[name], childPath)
+		}
+	}
+
+	if limit, ok := number(schema["minProperties"]); ok && float64(len(object)) < limit {
+		v.fail(path, "must have at least %v properties", limit)
+	}
+	if limit, ok := number(schema["maxProperties"]); ok && float64(len(object)) > limit {
+		v.fail(path, "must have at most %v properties", limit)
+	}
+}
+
+func (v *validator) checkArray(schema map[string]interface{}, array []interface{}, path string) {
+	prefix, _ := schema["prefixItems"].([]interface{})
+	for i, item := range array {
+		itemPath := path + "[" + strconv.Itoa(i) + "]"
+		if i < len(prefix) {
+			v.check(prefix[i], item, itemPath)
+		} else if items, ok := schema["items"]; ok {
+			v.check(items, item, itemPath)
+		}
+	}
+
+	if limit, ok := number(schema["minItems"]); ok && float64(len(array)) < limit {
+		v.fail(path, "must have at least %v items", limit)
+	}
+	if limit, ok := number(schema["maxItems"]); ok && float64(len(array)) > limit {
+		v.fail(path, "must have at most %v items", limit)
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := range array {
+			for j := i + 1; j < len(array); j++ {
+				if reflect.DeepEqual(array[i], array[j]) {
+					v.fail(path, "items %d and %d are equal", i, j)
+				}
+			}
+		}
+	}
+}
+
+func (v *validator) checkString(schema map[string]interface{}, text string, path string) {
+	length := float64(len([]rune(text)))
+	if limit, ok := number(schema["minLength"]); ok && length < limit {
+		v.fail(path, "must be at least %v characters long", limit)
+	}
+	if limit, ok := number(schema["maxLength"]); ok && length > limit {
+		v.fail(path, "must be at most %v characters long", limit)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.fail(path, "the schema's pattern %q is invalid", pattern)
+		} else if !re.MatchString(text) {
+			v.fail(path, "must match the pattern %q", pattern)
+		}
+	}
+}
+
+func (v *validator) checkNumber(schema map[string]interface{}, n float64, path string) {
+	if limit, ok := number(schema["minimum"]); ok && n < limit {
+		v.fail(path, "must be at least %v", limit)
+	}
+	if limit, ok := number(schema["maximum"]); ok && n > limit {
+		v.fail(path, "must be at most %v", limit)
+	}
+	if limit, ok := number(schema["exclusiveMinimum"]); ok && n <= limit {
+		v.fail(path, "must be greater than %v", limit)
+	}
+	if limit, ok := number(schema["exclusiveMaximum"]); ok && n >= limit {
+		v.fail(path, "must be less than %v", limit)
+	}
+	if factor, ok := number(schema["multipleOf"]); ok && factor > 0 {
+		if quotient := n / factor; math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.fail(path, "must be a multiple of %v", factor)
+		}
+	}
+}
+
+func (v *validator) checkCombinators(schema map[string]interface{}, value interface{}, path string) {
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			v.check(sub, value, path)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if v.matches(sub, value, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "does not match any of the allowed schemas")
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		count := 0
+		for _, sub := range oneOf {
+			if v.matches(sub, value, path) {
+				count++
+			}
+		}
+		if count != 1 {
+			v.fail(path, "must match exactly one of the allowed schemas, matches %d", count)
+		}
+	}
+	if not, ok := schema["not"]; ok && v.matches(not, value, path) {
+		v.fail(path, "matches a schema it must not match")
+	}
+}
+
+// resolve follows a local $ref such as "#/$defs/item".
+func (v *validator) resolve(ref string) (interface{}, error) {
+	pointer, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil, fmt.Errorf("only local $ref pointers are supported, not %q", ref)
+	}
+
+	node := v.root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("$ref %q does not resolve", ref)
+		}
+		if node, ok = object[token]; !ok {
+			return nil, fmt.Errorf("$ref %q does not resolve", ref)
+		}
+	}
+	return node, nil
+}
+
+func matchesType(types, value interface{}) bool {
+	switch types := types.(type) {
+	case string:
+		return isType(types, value)
+	case []interface{}:
+		for _, t := range types {
+			if name, ok := t.(string); ok && isType(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func isType(name string, value interface{}) bool {
+	switch name {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return typeOf(value) == name
+	}
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "unknown"
+}
+
+func describeType(types interface{}) string {
+	if list, ok := types.([]interface{}); ok {
+		names := make([]string, 0, len(list))
+		for _, t := range list {
+			names = append(names, fmt.Sprint(t))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(types)
+}
+
+func containsValue(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func number(value interface{}) (float64, bool) {
+	n, ok := value.(float64)
+	return n, ok
+}
+
+// compact renders a value as JSON for problem messages.
+func compact(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
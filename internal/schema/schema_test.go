@@ -0,0 +1,171 @@
+package schema
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		value  string
+		// problem is part of the expected problem, or empty if the value
+		// matches
+		problem string
+	}{
+		{"type string", `{"type": "string"}`, `"hi"`, ""},
+		{"type mismatch", `{"type": "string"}`, `1`, "$: expected string, got number"},
+		{"type integer", `{"type": "integer"}`, `3`, ""},
+		{"type integer fraction", `{"type": "integer"}`, `3.5`, "expected integer"},
+		{"type number accepts integer", `{"type": "number"}`, `3`, ""},
+		{"type list", `{"type": ["string", "null"]}`, `null`, ""},
+		{"type list mismatch", `{"type": ["string", "null"]}`, `true`, "expected string or null, got boolean"},
+
+		{"required present", `{"type": "object", "required": ["a"]}`, `{"a": 1}`, ""},
+		{"required missing", `{"type": "object", "required": ["a", "b"]}`, `{"a": 1}`, `missing required property "b"`},
+
+		{"enum match", `{"enum": ["red", "green"]}`, `"green"`, ""},
+		{"enum mismatch", `{"enum": ["red", "green"]}`, `"blue"`, `must be one of ["red","green"]`},
+		{"enum numbers", `{"enum": [1, 2]}`, `2`, ""},
+		{"const", `{"const": {"a": 1}}`, `{"a": 2}`, `must be {"a":1}`},
+
+		{"additionalProperties false", `{"properties": {"a": {}}, "additionalProperties": false}`, `{"a": 1, "b": 2}`, `property "b" is not allowed`},
+		{"additionalProperties allowed", `{"properties": {"a": {}}}`, `{"a": 1, "b": 2}`, ""},
+		{"additionalProperties schema", `{"additionalProperties": {"type": "number"}}`, `{"b": "x"}`, "$.b: expected number"},
+		{"property type", `{"properties": {"age": {"type": "integer", "minimum": 0}}}`, `{"age": -1}`, "$.age: must be at least 0"},
+
+		{"nested arrays", `{"type": "array", "items": {"type": "array", "items": {"type": "integer"}}}`, `[[1, 2], [3]]`, ""},
+		{"nested arrays mismatch", `{"type": "array", "items": {"type": "array", "items": {"type": "integer"}}}`, `[[1, 2], [3, "x"]]`, "$[1][1]: expected integer, got string"},
+		{"array of objects", `{"items": {"type": "object", "required": ["id"]}}`, `[{"id": 1}, {}]`, `$[1]: missing required property "id"`},
+		{"prefixItems", `{"prefixItems": [{"type": "string"}], "items": {"type": "number"}}`, `["a", 1, 2]`, ""},
+		{"prefixItems mismatch", `{"prefixItems": [{"type": "string"}], "items": {"type": "number"}}`, `[1, 1]`, "$[0]: expected string"},
+		{"minItems", `{"minItems": 2}`, `[1]`, "must have at least 2 items"},
+		{"uniqueItems", `{"uniqueItems": true}`, `[1, 2, 1]`, "items 0 and 2 are equal"},
+
+		{"string length", `{"maxLength": 3}`, `"héllo"`, "must be at most 3 characters long"},
+		{"pattern", `{"pattern": "^[a-z]+$"}`, `"abc1"`, "must match the pattern"},
+		{"multipleOf", `{"multipleOf": 0.1}`, `0.3`, ""},
+		{"exclusiveMaximum", `{"exclusiveMaximum": 10}`, `10`, "must be less than 10"},
+
+		{"ref to defs", `{"$defs": {"item": {"type": "string"}}, "type": "array", "items": {"$ref": "#/$defs/item"}}`, `["a", "b"]`, ""},
+		{"ref to defs mismatch", `{"$defs": {"item": {"type": "string"}}, "type": "array", "items": {"$ref": "#/$defs/item"}}`, `["a", 2]`, "$[1]: expected string"},
+		{"ref escaped pointer", `{"definitions": {"a/b": {"type": "null"}}, "$ref": "#/definitions/a~1b"}`, `null`, ""},
+		{"ref recursive", `{"type": "object", "properties": {"child": {"$ref": "#"}}, "additionalProperties": false}`, `{"child": {"child": {"x": 1}}}`, `$.child.child: property "x" is not allowed`},
+		{"ref unresolved", `{"$ref": "#/$defs/missing"}`, `1`, "does not resolve"},
+		{"ref remote", `{"$ref": "https://example.com/schema.json"}`, `1`, "only local $ref pointers"},
+		{"ref cycle", `{"$defs": {"a": {"$ref": "#/$defs/a"}}, "$ref": "#/$defs/a"}`, `1`, "nests too deeply"},
+
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "number"}]}`, `true`, "does not match any"},
+		{"oneOf", `{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `1`, "matches 2"},
+		{"not", `{"not": {"type": "null"}}`, `null`, "must not match"},
+		{"false subschema", `{"properties": {"a": false}}`, `{"a": 1}`, "$.a: no value is allowed here"},
+		{"unknown keywords ignored", `{"type": "string", "format": "email"}`, `"not an email"`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse([]byte(tt.schema))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			err = s.ValidateJSON([]byte(tt.value))
+			if tt.problem == "" {
+				if err != nil {
+					t.Errorf("ValidateJSON(%s) = %v, want no problems", tt.value, err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateJSON(%s) = %v, want a ValidationError", tt.value, err)
+			}
+			if !strings.Contains(verr.Error(), tt.problem) {
+				t.Errorf("ValidateJSON(%s) = %q, want a problem containing %q", tt.value, verr.Error(), tt.problem)
+			}
+		})
+	}
+}
+
+func TestValidateCapsProblems(t *testing.T) {
+	s, err := Parse([]byte(`{"items": {"type": "string"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var verr *ValidationError
+	if err := s.ValidateJSON([]byte(`[1,2,3,4,5,6,7,8,9,10,11,12]`)); !errors.As(err, &verr) {
+		t.Fatalf("ValidateJSON = %v, want a ValidationError", err)
+	}
+	if len(verr.Problems) != maxProblems+1 || verr.Problems[maxProblems] != "and 2 more problems" {
+		t.Errorf("problems = %q, want %d and a summary", verr.Problems, maxProblems)
+	}
+}
+
+func TestValidateJSONRejectsInvalidJSON(t *testing.T) {
+	s, err := Parse([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ValidateJSON([]byte(`{"a": `)); err == nil || !strings.Contains(err.Error(), "not valid JSON") {
+		t.Errorf("ValidateJSON = %v, want a not valid JSON problem", err)
+	}
+}
+
+func TestParseRejectsNonObjects(t *testing.T) {
+	for _, doc := range []string{`true`, `false`, `[]`, `"string"`, `1`, `null`, `{`} {
+		if _, err := Parse([]byte(doc)); err == nil {
+			t.Errorf("Parse(%s) succeeded, want an error", doc)
+		}
+	}
+	if _, err := Parse([]byte(`{}`)); err != nil {
+		t.Errorf("Parse({}) = %v", err)
+	}
+}
+
+func TestSchemaName(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"person.schema.json", "person"},
+		{"/tmp/my schema.json", "my_schema"},
+		{"ünïcode.json", "_n_code"},
+		{".hidden.json", "response"},
+		{strings.Repeat("a", 100) + ".json", strings.Repeat("a", 64)},
+	}
+	for _, tt := range tests {
+		got := schemaName(tt.path)
+		if got != tt.want {
+			t.Errorf("schemaName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+		if !valid.MatchString(got) {
+			t.Errorf("schemaName(%q) = %q, which providers reject", tt.path, got)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.schema.json")
+	if err := os.WriteFile(path, []byte(`{"type": "object"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if s.Name != "invoice" {
+		t.Errorf("Name = %q, want invoice", s.Name)
+	}
+
+	if err := os.WriteFile(path, []byte(`true`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invoice.schema.json") {
+		t.Errorf("Load of a boolean schema = %v, want an error naming the file", err)
+	}
+}
@@ -0,0 +1,457 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	// maxReadBytes caps how much of a file read_file returns.
+	maxReadBytes = 100 * 1024
+	// maxListEntries caps the entries list_dir returns.
+	maxListEntries = 500
+	// maxGrepMatches caps the matching lines grep returns.
+	maxGrepMatches = 200
+	// maxGrepFileSize skips files too large to be source code.
+	maxGrepFileSize = 1024 * 1024
+	// maxPatchFileSize caps the files patch_file loads to edit.
+	maxPatchFileSize = 10 * 1024 * 1024
+	// sniffBytes is how much of a file isBinary looks at.
+	sniffBytes = 8000
+)
+
+// skippedDirs are not searched by grep.
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// RegisterFileTools adds tools that read, list, search, write and patch
+// files inside root. Paths outside root are refused, and writes need the
+// user's approval.
+func RegisterFileTools(r *Registry, root string) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	ws := workspace{root: root}
+
+	r.Register(Tool{
+		Name:        "read_file",
+		Description: "Read a text file in the project. Paths are relative to the project root. Use offset and limit to read part of a large file.",
+		Schema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"path": {"type": "string", "description": "File path relative to the project root"},
+				"offset": {"type": "integer", "description": "First line to read, starting at 1"},
+				"limit": {"type": "integer", "description": "Maximum number of lines to read"}
+			},
+			"required": ["path"]
+		}`),
+		Handler: ws.readFile,
+	})
+	r.Register(Tool{
+		Name:        "list_dir",
+		Description: "List the entries of a directory in the project. Directories end with a slash.",
+		Schema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"path": {"type": "string", "description": "Directory path relative to the project root; defaults to the root"}
+			}
+		}`),
+		Handler: ws.listDir,
+	})
+	r.Register(Tool{
+		Name:        "grep",
+		Description: "Search the project's files for lines matching a regular expression. Returns file:line: text for each match.",
+		Schema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"pattern": {"type": "string", "description": "Go regular expression to search for"},
+				"path": {"type": "string", "description": "Directory or file to search; defaults to the project root"},
+				"glob": {"type": "string", "description": "Only search files whose name matches this glob, such as *.go"}
+			},
+			"required": ["pattern"]
+		}`),
+		Handler: ws.grep,
+	})
+	r.Register(Tool{
+		Name:        "write_file",
+		Description: "Create a file in the project or replace its whole content. Parent directories are created as needed.",
+		Schema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"path": {"type": "string", "description": "File path relative to the project root"},
+				"content": {"type": "string", "description": "The complete new content of the file"}
+			},
+			"required": ["path", "content"]
+		}`),
+		Handler:    ws.writeFile,
+		Permission: askAlways,
+	})
+	r.Register(Tool{
+		Name:        "patch_file",
+		Description: "Replace one exact occurrence of old_text with new_text in a project file. old_text must match exactly once; include surrounding lines to make it unique.",
+		Schema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"path": {"type": "string", "description": "File path relative to the project root"},
+				"old_text": {"type": "string", "description": "Text to replace, matching the file exactly"},
+				"new_text": {"type": "string", "description": "Replacement text"}
+			},
+			"required": ["path", "old_text", "new_text"]
+		}`),
+		Handler:    ws.patchFile,
+		Permission: askAlways,
+	})
+}
+
+func askAlways(json.RawMessage) Permission {
+	return PermissionAsk
+}
+
+// workspace confines file access to a project root.
+type workspace struct {
+	root string
+}
+
+// resolve turns path into an absolute path inside the root. Symlinks are
+// followed so that they cannot point out of the project; for files that do
+// not exist yet the nearest existing parent is checked.
+func (w workspace) resolve(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(w.root, path)
+	}
+	path = filepath.Clean(path)
+
+	existing, rest := path, ""
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			path = filepath.Join(resolved, rest)
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the project root", path)
+	}
+	return path, nil
+}
+
+// rel returns path relative to the root for display.
+func (w workspace) rel(path string) string {
+	if rel, err := filepath.Rel(w.root, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+func (w workspace) readFile(_ context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Path   string `json:"path"`
+		Offset int    `json:"offset"`
+		Limit  int    `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", err
+	}
+	path, err := w.resolve(in.Path)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := openRegular(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	// Only the lines asked for are kept, so that reading the start of a
+	// huge log does not load all of it
+	r := bufio.NewReaderSize(file, 64*1024)
+	if head, _ := r.Peek(sniffBytes); isBinary(head) {
+		return "", fmt.Errorf("%s is not a text file", w.rel(path))
+	}
+	content, err := readLines(r, max(in.Offset, 1), in.Limit)
+	if err != nil {
+		return "", err
+	}
+	if len(content) > maxReadBytes {
+		content = content[:maxReadBytes] + fmt.Sprintf("\n… truncated at %d bytes; use offset and limit to read the rest", maxReadBytes)
+	}
+	return content, nil
+}
+
+// openRegular opens path for reading if it is a regular file, refusing
+// devices and pipes whose reads may never end.
+func openRegular(path string) (*os.File, error) {
+	// Checked before opening, since opening a pipe blocks until it has a
+	// writer
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", filepath.Base(path))
+	}
+	return os.Open(path)
+}
+
+// readLines reads limit lines, or all when limit is 0, from line start on.
+// It stops once it has more than maxReadBytes.
+func readLines(r *bufio.Reader, start, limit int) (string, error) {
+	var b strings.Builder
+	line, count := 1, 0
+	for b.Len() <= maxReadBytes && (limit <= 0 || count < limit) {
+		chunk, err := r.ReadSlice('\n')
+		if line >= start {
+			b.Write(chunk[:min(len(chunk), maxReadBytes+1-b.Len())])
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			// The rest of a long line follows
+			continue
+		}
+		if len(chunk) > 0 && chunk[len(chunk)-1] == '\n' {
+			if line >= start {
+				count++
+			}
+			line++
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+func (w workspace) listDir(_ context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", err
+	}
+	path, err := w.resolve(in.Path)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, entry := range entries {
+		if i == maxListEntries {
+			fmt.Fprintf(&b, "… %d more entries\n", len(entries)-maxListEntries)
+			break
+		}
+		if entry.IsDir() {
+			b.WriteString(entry.Name() + "/\n")
+			continue
+		}
+		size := int64(0)
+		if info, err := entry.Info(); err == nil {
+			size = info.Size()
+		}
+		fmt.Fprintf(&b, "%s (%d bytes)\n", entry.Name(), size)
+	}
+	if b.Len() == 0 {
+		return "(empty directory)", nil
+	}
+	return b.String(), nil
+}
+
+// errEnoughMatches stops the walk once maxGrepMatches lines were found.
+var errEnoughMatches = errors.New("enough matches")
+
+func (w workspace) grep(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Pattern string `json:"pattern"`
+		Path    string `json:"path"`
+		Glob    string `json:"glob"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", err
+	}
+	re, err := regexp.Compile(in.Pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+	start, err := w.resolve(in.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var matches []string
+	err = filepath.WalkDir(start, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() {
+			if path != start && skippedDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// A symlink may point outside the root, so it is not followed, and
+		// reading a pipe or device could block
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		if in.Glob != "" {
+			if ok, _ := filepath.Match(in.Glob, entry.Name()); !ok {
+				return nil
+			}
+		}
+		if info, err := entry.Info(); err != nil || info.Size() > maxGrepFileSize {
+			return nil
+		}
+
+		data, err := readAtMost(path, maxGrepFileSize)
+		if err != nil || len(data) > maxGrepFileSize || isBinary(data) {
+			return nil
+		}
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		scanner.Buffer(make([]byte, 0, 64*1024), maxGrepFileSize)
+		for line := 1; scanner.Scan(); line++ {
+			if re.MatchString(scanner.Text()) {
+				matches = append(matches, fmt.Sprintf("%s:%d: %s", w.rel(path), line, strings.TrimSpace(scanner.Text())))
+				if len(matches) == maxGrepMatches {
+					return errEnoughMatches
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnoughMatches) {
+		return "", err
+	}
+
+	if len(matches) == 0 {
+		return "No matches.", nil
+	}
+	result := strings.Join(matches, "\n")
+	if len(matches) == maxGrepMatches {
+		result += fmt.Sprintf("\n… stopped after %d matches", maxGrepMatches)
+	}
+	return result, nil
+}
+
+func (w workspace) writeFile(_ context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Path    string `json:"path"`
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", err
+	}
+	path, err := w.resolve(in.Path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(in.Content), fileMode(path)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Wrote %d bytes to %s", len(in.Content), w.rel(path)), nil
+}
+
+func (w workspace) patchFile(_ context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Path    string `json:"path"`
+		OldText string `json:"old_text"`
+		NewText string `json:"new_text"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", err
+	}
+	if in.OldText == "" {
+		return "", errors.New("old_text must not be empty")
+	}
+	path, err := w.resolve(in.Path)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := readAtMost(path, maxPatchFileSize)
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxPatchFileSize {
+		return "", fmt.Errorf("%s is larger than %d bytes and cannot be patched", w.rel(path), maxPatchFileSize)
+	}
+	content := string(data)
+	switch count := strings.Count(content, in.OldText); count {
+	case 0:
+		return "", fmt.Errorf("old_text was not found in %s", w.rel(path))
+	case 1:
+	default:
+		return "", fmt.Errorf("old_text matches %d places in %s; include more context", count, w.rel(path))
+	}
+
+	content = strings.Replace(content, in.OldText, in.NewText, 1)
+	if err := os.WriteFile(path, []byte(content), fileMode(path)); err != nil {
+		return "", err
+	}
+	return "Patched " + w.rel(path), nil
+}
+
+// fileMode keeps the permissions of an existing file.
+func fileMode(path string) os.FileMode {
+	if info, err := os.Stat(path); err == nil {
+		return info.Mode().Perm()
+	}
+	return 0o644
+}
+
+// readAtMost reads the regular file at path, stopping one byte past limit
+// so that callers can tell it was cut.
+func readAtMost(path string, limit int) ([]byte, error) {
+	file, err := openRegular(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, int64(limit)+1))
+}
+
+// isBinary guesses whether data is binary from NUL bytes near the start.
+func isBinary(data []byte) bool {
+	head := data[:min(len(data), sniffBytes)]
+	return strings.IndexByte(string(head), 0) >= 0
+}
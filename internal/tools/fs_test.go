@@ -0,0 +1,133 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testWorkspace returns a workspace rooted in a new directory holding files.
+func testWorkspace(t *testing.T, files map[string]string) workspace {
+	t.Helper()
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return workspace{root: root}
+}
+
+func call(t *testing.T, handler Handler, args interface{}) (string, error) {
+	t.Helper()
+	data, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler(context.Background(), data)
+}
+
+func TestReadFile(t *testing.T) {
+	w := testWorkspace(t, map[string]string{
+		"lines.txt":  "one\ntwo\nthree\nfour",
+		"binary.bin": "ab\x00cd",
+	})
+
+	tests := []struct {
+		name   string
+		args   map[string]interface{}
+		want   string
+		errStr string
+	}{
+		{"whole file", map[string]interface{}{"path": "lines.txt"}, "one\ntwo\nthree\nfour", ""},
+		{"offset", map[string]interface{}{"path": "lines.txt", "offset": 3}, "three\nfour", ""},
+		{"limit", map[string]interface{}{"path": "lines.txt", "limit": 2}, "one\ntwo\n", ""},
+		{"offset and limit", map[string]interface{}{"path": "lines.txt", "offset": 2, "limit": 2}, "two\nthree\n", ""},
+		{"offset past the end", map[string]interface{}{"path": "lines.txt", "offset": 10}, "", ""},
+		{"binary", map[string]interface{}{"path": "binary.bin"}, "", "not a text file"},
+		{"outside the root", map[string]interface{}{"path": "../x"}, "", "outside the project root"},
+		{"missing", map[string]interface{}{"path": "nope.txt"}, "", "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := call(t, w.readFile, tt.args)
+			if tt.errStr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errStr) {
+					t.Errorf("err = %v, want one containing %q", err, tt.errStr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("readFile = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFileCapsLargeFiles(t *testing.T) {
+	line := strings.Repeat("x", 99) + "\n"
+	w := testWorkspace(t, map[string]string{
+		"big.log":  strings.Repeat(line, 5000),
+		"long.txt": strings.Repeat("y", 3*maxReadBytes),
+	})
+
+	got, err := call(t, w.readFile, map[string]interface{}{"path": "big.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, line) || !strings.Contains(got, "truncated at") || len(got) > maxReadBytes+200 {
+		t.Errorf("big.log: got %d bytes ending %q", len(got), got[len(got)-80:])
+	}
+
+	got, err = call(t, w.readFile, map[string]interface{}{"path": "big.log", "offset": 4999, "limit": 5})
+	if err != nil || got != line+line {
+		t.Errorf("end of big.log = %q, %v", got, err)
+	}
+
+	// A line longer than the read buffer
+	got, err = call(t, w.readFile, map[string]interface{}{"path": "long.txt", "limit": 1})
+	if err != nil || !strings.HasPrefix(got, strings.Repeat("y", maxReadBytes)+"\n… truncated") {
+		t.Errorf("long.txt: got %d bytes, %v", len(got), err)
+	}
+}
+
+func TestGrep(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("needle outside\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	w := testWorkspace(t, map[string]string{
+		"a.go":              "package a\n// needle here\n",
+		"sub/b.txt":         "no\nneedle two\n",
+		"node_modules/c.js": "needle skipped\n",
+		"big.txt":           "needle\n" + strings.Repeat("z", maxGrepFileSize),
+		"bin.dat":           "needle\x00",
+	})
+	if err := os.Symlink(outside, filepath.Join(w.root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := call(t, w.grep, map[string]interface{}{"pattern": "needle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a.go:2: // needle here\nsub/b.txt:2: needle two"
+	if got != want {
+		t.Errorf("grep = %q, want %q", got, want)
+	}
+
+	got, err = call(t, w.grep, map[string]interface{}{"pattern": "needle", "glob": "*.txt"})
+	if err != nil || got != "sub/b.txt:2: needle two" {
+		t.Errorf("grep *.txt = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package tools
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestReadFileRefusesPipes(t *testing.T) {
+	w := testWorkspace(t, nil)
+	if err := syscall.Mkfifo(filepath.Join(w.root, "pipe"), 0o644); err != nil {
+		t.Skip("no named pipes:", err)
+	}
+	if _, err := call(t, w.readFile, map[string]interface{}{"path": "pipe"}); err == nil || !strings.Contains(err.Error(), "not a regular file") {
+		t.Errorf("err = %v, want not a regular file", err)
+	}
+	if got, err := call(t, w.grep, map[string]interface{}{"pattern": "x"}); err != nil || got != "No matches." {
+		t.Errorf("grep = %q, %v; want no matches", got, err)
+	}
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"Chat2/internal/api"
+)
+
+// Handler runs a tool with the JSON arguments chosen by the model and
+// returns the result text that is sent back to it.
+type Handler func(ctx context.Context, args json.RawMessage) (string, error)
+
+// Permission says whether a tool call may run.
+type Permission int
+
+const (
+	// PermissionAllow runs the call without asking.
+	PermissionAllow Permission = iota
+	// PermissionAsk runs the call only once the user approves it.
+	PermissionAsk
+	// PermissionDeny refuses the call.
+	PermissionDeny
+)
+
+// Tool is a function the model can call.
+type Tool struct {
+	Name        string
+	Description string
+	// Schema is the JSON schema of the arguments object.
+	Schema  json.RawMessage
+	Handler Handler
+	// Permission decides per call whether the user must approve it. Nil
+	// allows every call.
+	Permission func(args json.RawMessage) Permission
+	// AskEveryTime leaves out approving the tool for the rest of the
+	// session, for tools such as run_command whose calls can do anything.
+	AskEveryTime bool
+}
+
+// OutputFunc receives the output of a running tool as it is produced.
+type OutputFunc func(chunk string)
+
+type outputKey struct{}
+
+// WithOutput returns a context through which tool handlers stream their
+// output to fn while they run.
+func WithOutput(ctx context.Context, fn OutputFunc) context.Context {
+	return context.WithValue(ctx, outputKey{}, fn)
+}
+
+// streamOutput passes chunk to the OutputFunc of ctx, if any.
+func streamOutput(ctx context.Context, chunk string) {
+	if fn, ok := ctx.Value(outputKey{}).(OutputFunc); ok {
+		fn(chunk)
+	}
+}
+
+// Registry holds the tools offered to the model, in registration order.
+type Registry struct {
+	tools []Tool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds tool, replacing any tool with the same name.
+func (r *Registry) Register(tool Tool) {
+	for i, existing := range r.tools {
+		if existing.Name == tool.Name {
+			r.tools[i] = tool
+			return
+		}
+	}
+	r.tools = append(r.tools, tool)
+}
+
+// Get returns the tool called name.
+func (r *Registry) Get(name string) (Tool, bool) {
+	for _, tool := range r.tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return Tool{}, false
+}
+
+// Len returns the number of registered tools.
+func (r *Registry) Len() int {
+	return len(r.tools)
+}
+
+// Definitions describes the registered tools for a chat request.
+func (r *Registry) Definitions() []api.ToolDefinition {
+	defs := make([]api.ToolDefinition, 0, len(r.tools))
+	for _, tool := range r.tools {
+		defs = append(defs, api.ToolDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.Schema,
+		})
+	}
+	return defs
+}
+
+// Permission returns whether call may run. Unknown tools are allowed so
+// that Run can report them to the model.
+func (r *Registry) Permission(call api.ToolCall) Permission {
+	tool, ok := r.Get(call.Name)
+	if !ok || tool.Permission == nil {
+		return PermissionAllow
+	}
+	return tool.Permission(json.RawMessage(call.Arguments))
+}
+
+// CanApproveAlways reports whether calls of the tool called name may be
+// approved for the rest of the session.
+func (r *Registry) CanApproveAlways(name string) bool {
+	tool, ok := r.Get(name)
+	return !ok || !tool.AskEveryTime
+}
+
+// Run calls the tool named by call. Errors are meant to be reported back to
+// the model as the call's result so it can correct itself.
+func (r *Registry) Run(ctx context.Context, call api.ToolCall) (string, error) {
+	tool, ok := r.Get(call.Name)
+	if !ok {
+		return "", fmt.Errorf("unknown tool %q", call.Name)
+	}
+
+	args := json.RawMessage(strings.TrimSpace(call.Arguments))
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(args, &object); err != nil {
+		return "", fmt.Errorf("arguments for %s are not a JSON object: %w", call.Name, err)
+	}
+
+	return tool.Handler(ctx, args)
+}
@@ -0,0 +1,257 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultShellTimeout bounds a command when no timeout is configured.
+	DefaultShellTimeout = 60 * time.Second
+	// DefaultShellOutput caps the output kept from a command when no limit
+	// is configured.
+	DefaultShellOutput = 32 * 1024
+)
+
+// ShellOptions configures the run_command tool.
+type ShellOptions struct {
+	// Allow lists command prefixes that run without asking, such as
+	// "go test" or "git status".
+	Allow []string
+	// Deny lists command prefixes that are always refused.
+	Deny []string
+	// Timeout is the longest a command may run.
+	Timeout time.Duration
+	// MaxOutput caps the bytes of stdout and stderr kept.
+	MaxOutput int
+}
+
+// RegisterShellTool adds a tool that runs shell commands with root as the
+// working directory. Commands are not sandboxed: once approved they can
+// reach anything the user can. Output streams to the context's OutputFunc
+// while the command runs.
+func RegisterShellTool(r *Registry, root string, opts ShellOptions) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultShellTimeout
+	}
+	if opts.MaxOutput <= 0 {
+		opts.MaxOutput = DefaultShellOutput
+	}
+	shell := shellTool{root: root, opts: opts}
+
+	r.Register(Tool{
+		Name: "run_command",
+		Description: fmt.Sprintf("Run a shell command with the project directory as the working directory and return its combined stdout and stderr with the exit status. "+
+			"Commands are stopped after %s and output is cut at %d bytes. Use it for builds, tests and git queries, and keep to files inside the project.", opts.Timeout, opts.MaxOutput),
+		Schema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"command": {"type": "string", "description": "The shell command to run"}
+			},
+			"required": ["command"]
+		}`),
+		Handler:      shell.run,
+		Permission:   shell.permission,
+		AskEveryTime: true,
+	})
+}
+
+type shellTool struct {
+	root string
+	opts ShellOptions
+}
+
+// shellOperators split a command line into the commands it runs.
+var shellOperators = []string{"&&", "||", ";", "|", "&", "\n", "$(", "`", "(", ")"}
+
+// wrapperCommands run the command that follows them, so a denylisted
+// command could hide behind them.
+var wrapperCommands = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "command": true, "builtin": true,
+	"exec": true, "nice": true, "nohup": true, "time": true, "timeout": true,
+	"xargs": true, "stdbuf": true, "setsid": true, "eval": true,
+	"sh": true, "bash": true, "zsh": true,
+}
+
+// permission denies commands that run anything on the denylist and allows
+// a command without asking only if it is a single allowlisted command that
+// is not wrapped, quoted, redirected or given a path leading out of the
+// project. Anything else is asked about.
+func (s shellTool) permission(args json.RawMessage) Permission {
+	var in struct {
+		Command string `json:"command"`
+	}
+	if json.Unmarshal(args, &in) != nil {
+		return PermissionAsk
+	}
+
+	segments := splitCommands(in.Command)
+	for _, segment := range segments {
+		if denied(segment, s.opts.Deny) {
+			return PermissionDeny
+		}
+	}
+
+	if len(segments) != 1 || strings.ContainsAny(in.Command, "<>'\"\\$") || leavesRoot(segments[0]) {
+		return PermissionAsk
+	}
+	if first, _, _ := strings.Cut(segments[0], " "); wrapperCommands[filepath.Base(first)] {
+		return PermissionAsk
+	}
+	if matchesPrefix(segments[0], s.opts.Allow) {
+		return PermissionAllow
+	}
+	return PermissionAsk
+}
+
+func splitCommands(command string) []string {
+	for _, op := range shellOperators {
+		command = strings.ReplaceAll(command, op, "\x00")
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(command, "\x00") {
+		if segment = strings.Join(strings.Fields(segment), " "); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// denied reports whether segment runs a denylisted command. Quotes and
+// backslashes are removed first, and the denylist is matched from every
+// word on, so that "sudo rm -rf", "env rm -rf" and a quoted or escaped rm
+// are caught as well as "rm -rf". Commands that merely mention a denied one,
+// such as "echo rm -rf", are refused too.
+func denied(segment string, deny []string) bool {
+	words := strings.Fields(strings.NewReplacer(`"`, "", "'", "", `\`, "").Replace(segment))
+	for i, word := range words {
+		// "/bin/rm" runs rm
+		command := append([]string{filepath.Base(word)}, words[i+1:]...)
+		if matchesPrefix(strings.Join(command, " "), deny) {
+			return true
+		}
+	}
+	return false
+}
+
+// leavesRoot reports whether an argument of command may point outside the
+// working directory: an absolute or home path, a parent directory or a
+// variable such as $HOME. It is a guard for allowlisted commands, not a
+// sandbox.
+func leavesRoot(command string) bool {
+	for _, arg := range strings.Fields(command) {
+		arg = strings.Trim(arg, `"'`)
+		if _, value, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(arg, "-") {
+			arg = value
+		}
+		if strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, "~") || strings.Contains(arg, "$") || strings.HasPrefix(arg, `\`) || filepath.IsAbs(arg) {
+			return true
+		}
+		for _, part := range strings.FieldsFunc(arg, func(r rune) bool { return r == '/' || r == '\\' }) {
+			if part == ".." {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesPrefix reports whether command starts with one of prefixes as
+// whole words.
+func matchesPrefix(command string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		prefix = strings.Join(strings.Fields(prefix), " ")
+		if prefix != "" && (command == prefix || strings.HasPrefix(command, prefix+" ")) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s shellTool) run(ctx context.Context, args json.RawMessage) (string, error) {
+	var in struct {
+		Command string `json:"command"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(in.Command) == "" {
+		return "", errors.New("command must not be empty")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", in.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", in.Command)
+	}
+	cmd.Dir = s.root
+	// Background processes that keep the pipes open must not hang the tool
+	cmd.WaitDelay = 2 * time.Second
+
+	output := &cappedOutput{ctx: ctx, limit: s.opts.MaxOutput}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err := cmd.Run()
+
+	result := strings.TrimRight(output.String(), "\n")
+	if output.dropped > 0 {
+		result += fmt.Sprintf("\n… %d more bytes of output were cut", output.dropped)
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result += fmt.Sprintf("\n[timed out after %s]", s.opts.Timeout)
+	case ctx.Err() != nil:
+		return "", ctx.Err()
+	case errors.As(err, &exitErr):
+		result += fmt.Sprintf("\n[exit status %d]", exitErr.ExitCode())
+	case err != nil:
+		return "", err
+	default:
+		result += "\n[exit status 0]"
+	}
+	return strings.TrimLeft(result, "\n"), nil
+}
+
+// cappedOutput collects a command's output up to limit bytes and streams
+// what it keeps.
+type cappedOutput struct {
+	ctx     context.Context
+	limit   int
+	mu      sync.Mutex
+	buf     strings.Builder
+	dropped int
+}
+
+func (o *cappedOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	keep := min(len(p), o.limit-o.buf.Len())
+	if keep > 0 {
+		o.buf.Write(p[:keep])
+		streamOutput(o.ctx, string(p[:keep]))
+	}
+	o.dropped += len(p) - max(keep, 0)
+	return len(p), nil
+}
+
+func (o *cappedOutput) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.buf.String()
+}
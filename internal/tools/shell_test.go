@@ -0,0 +1,126 @@
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+	}{
+		{"go test ./...", []string{"go test ./..."}},
+		{"  go   test  ", []string{"go test"}},
+		{"go build && go test", []string{"go build", "go test"}},
+		{"false || rm -rf .", []string{"false", "rm -rf ."}},
+		{"ls; pwd", []string{"ls", "pwd"}},
+		{"cat x | grep y", []string{"cat x", "grep y"}},
+		{"sleep 1 & echo", []string{"sleep 1", "echo"}},
+		{"echo $(rm -rf .)", []string{"echo", "rm -rf ."}},
+		{"echo `whoami`", []string{"echo", "whoami"}},
+		{"(cd x) \n ls", []string{"cd x", "ls"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitCommands(tt.command); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommands(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestMatchesPrefix(t *testing.T) {
+	prefixes := []string{"go test", "git  status", ""}
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"go test", true},
+		{"go test ./...", true},
+		{"go testing", false},
+		{"go", false},
+		{"git status --short", true},
+		{"gitstatus", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := matchesPrefix(tt.command, prefixes); got != tt.want {
+			t.Errorf("matchesPrefix(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestLeavesRoot(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"go test ./...", false},
+		{"git diff main..feature", false},
+		{"cat internal/tools/fs.go", false},
+		{"cat /etc/passwd", true},
+		{"cat ../secret", true},
+		{"cat a/../../secret", true},
+		{"ls ~", true},
+		{"ls ~/.ssh", true},
+		{"cat $HOME/.netrc", true},
+		{"go test -coverprofile=/tmp/cover.out", true},
+		{`cat "/etc/passwd"`, true},
+		{`type \Windows\win.ini`, true},
+	}
+	for _, tt := range tests {
+		if got := leavesRoot(tt.command); got != tt.want {
+			t.Errorf("leavesRoot(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestShellPermission(t *testing.T) {
+	shell := shellTool{opts: ShellOptions{
+		Allow: []string{"go test", "git status", "python3", "ls"},
+		Deny:  []string{"rm -rf", "git push"},
+	}}
+	tests := []struct {
+		command string
+		want    Permission
+	}{
+		{"go test ./...", PermissionAllow},
+		{"git status", PermissionAllow},
+		{"ls", PermissionAllow},
+		{"go build ./...", PermissionAsk},
+		{"go test ./... && go vet ./...", PermissionAsk},
+		{"go test ./... > out.txt", PermissionAsk},
+		{"go test ../other/...", PermissionAsk},
+		{"ls /etc", PermissionAsk},
+		{`python3 -c "open('/etc/passwd').read()"`, PermissionAsk},
+		{"python3 tools/gen.py", PermissionAllow},
+		{"ls $HOME", PermissionAsk},
+		{"sudo ls", PermissionAsk},
+		{"env ls", PermissionAsk},
+		{"rm -rf .", PermissionDeny},
+		{"go test && rm -rf .", PermissionDeny},
+		{"echo $(rm -rf .)", PermissionDeny},
+		{"sudo rm -rf .", PermissionDeny},
+		{"sudo -u root rm -rf .", PermissionDeny},
+		{"env FOO=1 rm -rf .", PermissionDeny},
+		{"command rm -rf .", PermissionDeny},
+		{"nice -n 10 rm -rf .", PermissionDeny},
+		{"find . | xargs rm -rf", PermissionDeny},
+		{"/bin/rm -rf .", PermissionDeny},
+		{`sh -c 'r''m -rf .'`, PermissionDeny},
+		{`"rm" -rf .`, PermissionDeny},
+		{`r\m -rf .`, PermissionDeny},
+		{"git   push origin main", PermissionDeny},
+	}
+	for _, tt := range tests {
+		args, _ := json.Marshal(map[string]string{"command": tt.command})
+		if got := shell.permission(args); got != tt.want {
+			t.Errorf("permission(%q) = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+
+	if got := shell.permission(json.RawMessage(`{"command": 1}`)); got != PermissionAsk {
+		t.Errorf("permission of invalid arguments = %v, want PermissionAsk", got)
+	}
+}
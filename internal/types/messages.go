@@ -1,7 +1,10 @@
 package types
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -9,12 +12,12 @@ import (
 
 type (
 	ResponseMsg     string
-	StreamCharMsg   string
-	StreamEndMsg    struct{}
 	ErrorMsg        string
 	ProviderSetMsg  string
 	ConfigLoadedMsg struct{}
 	AnimationMsg    struct{}
+	// LocalModelsMsg lists "provider/model" targets found on this machine
+	LocalModelsMsg []string
 )
 
 type State int
@@ -26,13 +29,257 @@ const (
 	StateHelp
 	StateFileBrowser
 	StateExitConfirm
+	StateModelPicker
+	StateToolConfirm
+	StateCompare
+	StateSessions
 )
 
-type AIProvider struct {
-	Name    string
-	APIKey  string
-	BaseURL string
-	Model   string
+// Role identifies who authored a chat message.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+	RoleSystem    Role = "system"
+	RoleTool      Role = "tool"
+)
+
+// MessageStatus describes the lifecycle or kind of a chat message. Only
+// complete and interrupted messages are part of the conversation sent to a
+// provider.
+type MessageStatus int
+
+const (
+	StatusComplete MessageStatus = iota
+	StatusStreaming
+	// StatusInterrupted marks a reply the user stopped before it finished
+	StatusInterrupted
+	StatusNotice
+	StatusSuccess
+	StatusError
+	// StatusRunning marks a tool call whose result is not in yet
+	StatusRunning
+)
+
+// statusNames are the names statuses are saved under, so that saved
+// sessions do not depend on the order of the constants.
+var statusNames = map[MessageStatus]string{
+	StatusComplete:    "complete",
+	StatusStreaming:   "streaming",
+	StatusInterrupted: "interrupted",
+	StatusNotice:      "notice",
+	StatusSuccess:     "success",
+	StatusError:       "error",
+	StatusRunning:     "running",
+}
+
+func (s MessageStatus) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("MessageStatus(%d)", int(s))
+}
+
+func (s MessageStatus) MarshalJSON() ([]byte, error) {
+	name, ok := statusNames[s]
+	if !ok {
+		return nil, fmt.Errorf("unknown message status %d", int(s))
+	}
+	return json.Marshal(name)
+}
+
+// UnmarshalJSON reads a status name. Numbers, as sessions saved before
+// statuses had names hold them, are accepted too.
+func (s *MessageStatus) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		*s = MessageStatus(number)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for status, candidate := range statusNames {
+		if candidate == name {
+			*s = status
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown message status %q", name)
+}
+
+// TokenUsage is the token accounting reported by a provider for one turn,
+// or the running total of a session. PromptTokens includes CachedTokens.
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	CachedTokens     int `json:"cached_tokens,omitempty"`
+	// Cost is in US dollars. Priced is false when the model's price is
+	// unknown, in which case Cost only covers the priced turns.
+	Cost   float64 `json:"cost,omitempty"`
+	Priced bool    `json:"priced,omitempty"`
+}
+
+// Add accumulates other into u.
+func (u *TokenUsage) Add(other TokenUsage) {
+	if u.PromptTokens+u.CompletionTokens == 0 {
+		u.Priced = other.Priced
+	} else {
+		u.Priced = u.Priced && other.Priced
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.CachedTokens += other.CachedTokens
+	u.Cost += other.Cost
+}
+
+// TotalTokens returns prompt and completion tokens combined.
+func (u TokenUsage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// ErrorDetail describes a failed provider request so that the chat can
+// show what went wrong and how to fix it.
+type ErrorDetail struct {
+	Provider   string        `json:"provider,omitempty"`
+	Kind       string        `json:"kind,omitempty"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Type       string        `json:"type,omitempty"`
+	RequestID  string        `json:"request_id,omitempty"`
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+	// Hint suggests how the user can fix the problem.
+	Hint string `json:"hint,omitempty"`
+}
+
+// MarshalJSON saves RetryAfter as a duration such as "20s".
+func (d ErrorDetail) MarshalJSON() ([]byte, error) {
+	type plain ErrorDetail
+	saved := struct {
+		plain
+		RetryAfter string `json:"retry_after,omitempty"`
+	}{plain: plain(d)}
+	if d.RetryAfter > 0 {
+		saved.RetryAfter = d.RetryAfter.String()
+	}
+	return json.Marshal(saved)
+}
+
+// UnmarshalJSON reads RetryAfter as a duration. Numbers, as sessions saved
+// before hold nanoseconds, are accepted too.
+func (d *ErrorDetail) UnmarshalJSON(data []byte) error {
+	type plain ErrorDetail
+	saved := struct {
+		*plain
+		RetryAfter json.RawMessage `json:"retry_after,omitempty"`
+	}{plain: (*plain)(d)}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+
+	d.RetryAfter = 0
+	if len(saved.RetryAfter) == 0 {
+		return nil
+	}
+	var nanoseconds int64
+	if err := json.Unmarshal(saved.RetryAfter, &nanoseconds); err == nil {
+		d.RetryAfter = time.Duration(nanoseconds)
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(saved.RetryAfter, &text); err != nil {
+		return err
+	}
+	retryAfter, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("invalid retry_after %q", text)
+	}
+	d.RetryAfter = retryAfter
+	return nil
+}
+
+// Message is a single entry in a chat session.
+type Message struct {
+	Role      Role          `json:"role"`
+	Content   string        `json:"content"`
+	Timestamp time.Time     `json:"timestamp"`
+	Provider  string        `json:"provider,omitempty"`
+	Model     string        `json:"model,omitempty"`
+	Usage     *TokenUsage   `json:"usage,omitempty"`
+	Status    MessageStatus `json:"status,omitempty"`
+	// Error holds provider failure details for error messages.
+	Error *ErrorDetail `json:"error,omitempty"`
+	// FallbackFrom names the provider that failed before Provider answered
+	// in its place.
+	FallbackFrom string `json:"fallback_from,omitempty"`
+	// ToolCalls are the tools an assistant message asked to run.
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// Tool describes the call whose result a tool message holds.
+	Tool *ToolResult `json:"tool,omitempty"`
+	// Attachments are the files sent along with a user message.
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Schema names the JSON schema a structured reply was validated
+	// against.
+	Schema string `json:"schema,omitempty"`
+	// Truncated marks a reply cut off at the token limit.
+	Truncated bool `json:"truncated,omitempty"`
+	// Reasoning is the thinking a model streamed before its reply. It is
+	// only sent back to the model when the provider signed it with
+	// ReasoningSignature.
+	Reasoning          string `json:"reasoning,omitempty"`
+	ReasoningSignature string `json:"reasoning_signature,omitempty"`
+}
+
+// Attachment is a file attached to a user message. Images are sent to
+// vision models as they are; text files are inlined.
+type Attachment struct {
+	Name      string `json:"name"`
+	Path      string `json:"path,omitempty"`
+	MediaType string `json:"media_type"`
+	Data      []byte `json:"data"`
+}
+
+// IsImage reports whether the attachment is an image.
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(a.MediaType, "image/")
+}
+
+// ToolCall is a tool invocation requested by the assistant. Arguments holds
+// a JSON object.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolResult identifies the call a tool message answers and whether the
+// tool failed. The output itself is the message content.
+type ToolResult struct {
+	CallID    string `json:"call_id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Failed    bool   `json:"failed,omitempty"`
+}
+
+// IsNotice reports whether the message is UI feedback rather than a turn.
+func (m Message) IsNotice() bool {
+	return m.Status == StatusNotice || m.Status == StatusSuccess || m.Status == StatusError
+}
+
+// NewNotice creates an informational system notice.
+func NewNotice(text string) Message {
+	return Message{Role: RoleSystem, Content: text, Timestamp: time.Now(), Status: StatusNotice}
+}
+
+// NewSuccess creates a system notice confirming a completed action.
+func NewSuccess(text string) Message {
+	return Message{Role: RoleSystem, Content: text, Timestamp: time.Now(), Status: StatusSuccess}
+}
+
+// NewError creates a system notice reporting a failure.
+func NewError(text string) Message {
+	return Message{Role: RoleSystem, Content: text, Timestamp: time.Now(), Status: StatusError}
 }
 
 // UIModel interface defines the contract for UI models
@@ -40,14 +287,16 @@ type UIModel interface {
 	tea.Model
 	
 	// Message management
-	AddMessage(string)
+	AddMessage(Message)
 	ClearMessages()
-	GetMessages() []string
+	GetMessages() []Message
 	
 	// Provider management
 	GetCurrentProvider() string
 	GetAvailableProviders() []string
 	SwitchProvider() tea.Cmd
+	OpenModelPicker() tea.Cmd
+	SelectModel(string)
 	
 	// State management
 	GetState() State
@@ -62,12 +311,35 @@ type UIModel interface {
 	// File browser
 	GetFileBrowserPath() string
 	SetFileBrowserPath(string)
+	AttachFile(string)
+
+	// Structured output
+	SetJSONSchema(path string)
+	ClearJSONSchema()
+
+	// Generation parameters
+	DescribeParams() string
+	SetParam(name, value string) error
+	ResetParams()
+
+	// Agent mode
+	AgentMode() bool
+	SetAgentMode(bool)
+
+	// Saved sessions
+	OpenSessionBrowser() tea.Cmd
+	SessionTitle() string
+	SetSessionTitle(string)
+
+	// Model comparison
+	SetCompareTargets(targets []string)
+	ClearCompareTargets()
 }
 
 // Legacy model struct for compatibility
 type Model struct {
 	TextInput          textinput.Model
-	Messages           []string
+	Messages           []Message
 	Loading            bool
 	Streaming          bool
 	CurrentResponse    strings.Builder
@@ -84,13 +356,3 @@ type TeaMsg = tea.Msg
 type TeaModel = tea.Model
 type TeaCmd = tea.Cmd
 
-// Global program state for streaming responses
-var globalProgram *tea.Program
-
-func SetGlobalProgram(program *tea.Program) {
-	globalProgram = program
-}
-
-func GetGlobalProgram() *tea.Program {
-	return globalProgram
-}
@@ -0,0 +1,241 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"Chat2/internal/api"
+	"Chat2/internal/themes"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ModelPickerComponent is a searchable list of a provider's models.
+type ModelPickerComponent struct {
+	filter   textinput.Model
+	models   []api.ModelInfo
+	filtered []api.ModelInfo
+	cursor   int
+	provider string
+	current  string
+	loading  bool
+	err      string
+}
+
+func NewModelPickerComponent() *ModelPickerComponent {
+	ti := textinput.New()
+	ti.Placeholder = "Search models..."
+	ti.Prompt = "🔎 "
+	ti.CharLimit = 100
+
+	return &ModelPickerComponent{filter: ti}
+}
+
+// Open resets the picker for provider while its models load. current is the
+// model in use, which is marked in the list.
+func (p *ModelPickerComponent) Open(provider, current string) tea.Cmd {
+	p.provider = provider
+	p.current = current
+	p.models = nil
+	p.filtered = nil
+	p.cursor = 0
+	p.loading = true
+	p.err = ""
+	p.filter.SetValue("")
+	return p.filter.Focus()
+}
+
+// SetModels fills the picker with the loaded models, or shows err.
+func (p *ModelPickerComponent) SetModels(models []api.ModelInfo, err error) {
+	p.loading = false
+	p.err = ""
+	if err != nil {
+		p.err = err.Error()
+	}
+	p.models = models
+	p.applyFilter()
+
+	for i, model := range p.filtered {
+		if model.ID == p.current {
+			p.cursor = i
+			break
+		}
+	}
+}
+
+// Provider returns the provider whose models are listed.
+func (p *ModelPickerComponent) Provider() string {
+	return p.provider
+}
+
+// Selected returns the highlighted model. When nothing matches the search,
+// the search text itself is offered so unlisted model IDs can be used.
+func (p *ModelPickerComponent) Selected() (string, bool) {
+	if p.cursor < len(p.filtered) {
+		return p.filtered[p.cursor].ID, true
+	}
+	if query := strings.TrimSpace(p.filter.Value()); query != "" {
+		return query, true
+	}
+	return "", false
+}
+
+func (p *ModelPickerComponent) Update(msg tea.KeyMsg) (*ModelPickerComponent, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyUp, tea.KeyCtrlK:
+		if p.cursor > 0 {
+			p.cursor--
+		}
+		return p, nil
+	case tea.KeyDown, tea.KeyCtrlJ:
+		if p.cursor < len(p.filtered)-1 {
+			p.cursor++
+		}
+		return p, nil
+	case tea.KeyPgUp:
+		p.cursor = max(p.cursor-10, 0)
+		return p, nil
+	case tea.KeyPgDown:
+		p.cursor = max(min(p.cursor+10, len(p.filtered)-1), 0)
+		return p, nil
+	}
+
+	var cmd tea.Cmd
+	previous := p.filter.Value()
+	p.filter, cmd = p.filter.Update(msg)
+	if p.filter.Value() != previous {
+		p.applyFilter()
+		p.cursor = 0
+	}
+	return p, cmd
+}
+
+// applyFilter keeps the models whose ID or name contains every search term.
+func (p *ModelPickerComponent) applyFilter() {
+	terms := strings.Fields(strings.ToLower(p.filter.Value()))
+	p.filtered = p.filtered[:0]
+
+	for _, model := range p.models {
+		haystack := strings.ToLower(model.ID + " " + model.Name)
+		matches := true
+		for _, term := range terms {
+			if !strings.Contains(haystack, term) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			p.filtered = append(p.filtered, model)
+		}
+	}
+}
+
+func (p *ModelPickerComponent) View(width, height int) string {
+	theme := themes.GetCurrentTheme()
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Primary)).
+		Bold(true)
+	dimStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.DimText))
+	itemStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Text))
+	activeStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Background)).
+		Background(lipgloss.Color(theme.Primary)).
+		Bold(true)
+
+	var lines []string
+	lines = append(lines, titleStyle.Render("🧠 Models · "+strings.ToUpper(p.provider)))
+	lines = append(lines, p.filter.View())
+	lines = append(lines, "")
+
+	// Room for the title, search, footer and border padding
+	visible := height - 10
+	if visible < 3 {
+		visible = 3
+	}
+
+	switch {
+	case p.loading:
+		lines = append(lines, dimStyle.Render("Loading models..."))
+	case p.err != "" && len(p.models) == 0:
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Error)).Render("❌ "+p.err))
+	case len(p.filtered) == 0:
+		hint := "No models match."
+		if strings.TrimSpace(p.filter.Value()) != "" {
+			hint += " Press Enter to use the typed ID."
+		}
+		lines = append(lines, dimStyle.Render(hint))
+	default:
+		start := 0
+		if p.cursor >= visible {
+			start = p.cursor - visible + 1
+		}
+		end := min(start+visible, len(p.filtered))
+
+		for i := start; i < end; i++ {
+			row := formatModelRow(p.filtered[i], p.filtered[i].ID == p.current, width-8)
+			if i == p.cursor {
+				lines = append(lines, activeStyle.Render(row))
+			} else {
+				lines = append(lines, itemStyle.Render(row))
+			}
+		}
+		lines = append(lines, "", dimStyle.Render(fmt.Sprintf("%d of %d models", len(p.filtered), len(p.models))))
+	}
+
+	lines = append(lines, "", dimStyle.Render("↑↓ select • Enter use model • Ctrl+R refresh • Esc close"))
+
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(theme.Primary)).
+		Padding(1, 2).
+		Width(width)
+
+	return boxStyle.Render(strings.Join(lines, "\n"))
+}
+
+// formatModelRow renders one model with its context length, price per
+// million tokens and capability icons.
+func formatModelRow(model api.ModelInfo, current bool, width int) string {
+	marker := "  "
+	if current {
+		marker = "● "
+	}
+
+	var details []string
+	if model.ContextLength > 0 {
+		details = append(details, fmt.Sprintf("%dk ctx", model.ContextLength/1000))
+	}
+	if model.Pricing != nil {
+		if model.Pricing.Input == 0 && model.Pricing.Output == 0 {
+			details = append(details, "free")
+		} else {
+			details = append(details, fmt.Sprintf("$%.2f/$%.2f", model.Pricing.Input, model.Pricing.Output))
+		}
+	}
+	var icons string
+	if model.Vision {
+		icons += "👁"
+	}
+	if model.Tools {
+		icons += "🔧"
+	}
+	if model.Reasoning {
+		icons += "🧠"
+	}
+	if icons != "" {
+		details = append(details, icons)
+	}
+
+	info := strings.Join(details, "  ")
+	name := marker + model.ID
+	padding := width - lipgloss.Width(name) - lipgloss.Width(info)
+	if padding < 2 {
+		padding = 2
+	}
+	return name + strings.Repeat(" ", padding) + info
+}
@@ -0,0 +1,364 @@
+package components
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"Chat2/internal/chat"
+	"Chat2/internal/themes"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// previewLines is how much room the preview of the highlighted session
+// takes below the list.
+const previewLines = 6
+
+// SessionBrowserComponent is a fuzzy-searchable list of saved sessions with
+// a preview of the highlighted one. Renaming and deleting happen inline.
+type SessionBrowserComponent struct {
+	filter   textinput.Model
+	rename   textinput.Model
+	sessions []chat.SessionInfo
+	filtered []chat.SessionInfo
+	cursor   int
+	current  string
+	err      string
+
+	previewID string
+	preview   []string
+
+	renaming      bool
+	confirmDelete bool
+}
+
+func NewSessionBrowserComponent() *SessionBrowserComponent {
+	filter := textinput.New()
+	filter.Placeholder = "Search sessions..."
+	filter.Prompt = "🔎 "
+	filter.CharLimit = 100
+
+	rename := textinput.New()
+	rename.Prompt = "✏️  "
+	rename.CharLimit = 100
+
+	return &SessionBrowserComponent{filter: filter, rename: rename}
+}
+
+// Open fills the browser with sessions, or shows err. current is the ID of
+// the open session, which is marked in the list.
+func (b *SessionBrowserComponent) Open(sessions []chat.SessionInfo, current string, err error) tea.Cmd {
+	b.current = current
+	b.cursor = 0
+	b.renaming = false
+	b.confirmDelete = false
+	b.filter.SetValue("")
+	b.SetSessions(sessions, err)
+	return b.filter.Focus()
+}
+
+// SetSessions replaces the listed sessions, keeping the search and the
+// highlighted position.
+func (b *SessionBrowserComponent) SetSessions(sessions []chat.SessionInfo, err error) {
+	b.err = ""
+	if err != nil {
+		b.err = err.Error()
+	}
+	b.sessions = sessions
+	b.previewID = ""
+	b.applyFilter()
+	b.cursor = max(min(b.cursor, len(b.filtered)-1), 0)
+}
+
+// Selected returns the highlighted session.
+func (b *SessionBrowserComponent) Selected() (chat.SessionInfo, bool) {
+	if b.cursor < len(b.filtered) {
+		return b.filtered[b.cursor], true
+	}
+	return chat.SessionInfo{}, false
+}
+
+// PreviewID returns the session whose preview is shown.
+func (b *SessionBrowserComponent) PreviewID() string {
+	return b.previewID
+}
+
+// SetPreview shows lines as the preview of session id.
+func (b *SessionBrowserComponent) SetPreview(id string, lines []string) {
+	b.previewID = id
+	b.preview = lines
+}
+
+// StartRename edits the title of the highlighted session.
+func (b *SessionBrowserComponent) StartRename() tea.Cmd {
+	info, ok := b.Selected()
+	if !ok {
+		return nil
+	}
+	b.renaming = true
+	b.rename.SetValue(info.Title)
+	b.rename.CursorEnd()
+	b.filter.Blur()
+	return b.rename.Focus()
+}
+
+// Renaming reports whether a title is being edited, and returns it.
+func (b *SessionBrowserComponent) Renaming() (string, bool) {
+	return strings.TrimSpace(b.rename.Value()), b.renaming
+}
+
+// StartDelete asks to confirm deleting the highlighted session.
+func (b *SessionBrowserComponent) StartDelete() {
+	if _, ok := b.Selected(); ok {
+		b.confirmDelete = true
+	}
+}
+
+// ConfirmingDelete reports whether a deletion waits for confirmation.
+func (b *SessionBrowserComponent) ConfirmingDelete() bool {
+	return b.confirmDelete
+}
+
+// Cancel leaves renaming or confirming a deletion.
+func (b *SessionBrowserComponent) Cancel() tea.Cmd {
+	b.renaming = false
+	b.confirmDelete = false
+	b.rename.Blur()
+	return b.filter.Focus()
+}
+
+func (b *SessionBrowserComponent) Update(msg tea.KeyMsg) (*SessionBrowserComponent, tea.Cmd) {
+	if b.renaming {
+		var cmd tea.Cmd
+		b.rename, cmd = b.rename.Update(msg)
+		return b, cmd
+	}
+
+	switch msg.Type {
+	case tea.KeyUp, tea.KeyCtrlK:
+		if b.cursor > 0 {
+			b.cursor--
+		}
+		return b, nil
+	case tea.KeyDown, tea.KeyCtrlJ:
+		if b.cursor < len(b.filtered)-1 {
+			b.cursor++
+		}
+		return b, nil
+	case tea.KeyPgUp:
+		b.cursor = max(b.cursor-10, 0)
+		return b, nil
+	case tea.KeyPgDown:
+		b.cursor = max(min(b.cursor+10, len(b.filtered)-1), 0)
+		return b, nil
+	}
+
+	var cmd tea.Cmd
+	previous := b.filter.Value()
+	b.filter, cmd = b.filter.Update(msg)
+	if b.filter.Value() != previous {
+		b.applyFilter()
+		b.cursor = 0
+	}
+	return b, cmd
+}
+
+// applyFilter keeps the sessions whose title, first prompt or model fuzzily
+// match every search term, best matches first.
+func (b *SessionBrowserComponent) applyFilter() {
+	terms := strings.Fields(strings.ToLower(b.filter.Value()))
+	scores := make(map[string]int)
+	b.filtered = b.filtered[:0]
+
+	for _, info := range b.sessions {
+		haystack := strings.ToLower(info.Title + " " + info.Preview + " " + info.Model)
+		total, matches := 0, true
+		for _, term := range terms {
+			score, ok := fuzzyScore(term, haystack)
+			if !ok {
+				matches = false
+				break
+			}
+			total += score
+		}
+		if matches {
+			scores[info.ID] = total
+			b.filtered = append(b.filtered, info)
+		}
+	}
+
+	if len(terms) > 0 {
+		sort.SliceStable(b.filtered, func(i, j int) bool {
+			return scores[b.filtered[i].ID] > scores[b.filtered[j].ID]
+		})
+	}
+}
+
+// fuzzyScore reports whether the characters of query appear in text in
+// order. Matches score higher for adjacent characters and for characters
+// at the start of a word.
+func fuzzyScore(query, text string) (int, bool) {
+	runes := []rune(text)
+	score, pos, previous := 0, 0, -2
+	for _, q := range query {
+		for pos < len(runes) && runes[pos] != q {
+			pos++
+		}
+		if pos == len(runes) {
+			return 0, false
+		}
+		score++
+		if pos == previous+1 {
+			score += 3
+		}
+		if pos == 0 || !unicode.IsLetter(runes[pos-1]) && !unicode.IsDigit(runes[pos-1]) {
+			score += 2
+		}
+		previous = pos
+		pos++
+	}
+	return score, true
+}
+
+func (b *SessionBrowserComponent) View(width, height int) string {
+	theme := themes.GetCurrentTheme()
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Primary)).
+		Bold(true)
+	dimStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.DimText))
+	itemStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Text))
+	activeStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Background)).
+		Background(lipgloss.Color(theme.Primary)).
+		Bold(true)
+	warningStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Warning)).
+		Bold(true)
+
+	var lines []string
+	lines = append(lines, titleStyle.Render("📋 Sessions"))
+	if b.renaming {
+		lines = append(lines, b.rename.View())
+	} else {
+		lines = append(lines, b.filter.View())
+	}
+	lines = append(lines, "")
+
+	// Room for the title, search, preview, footer and border padding
+	visible := height - 12 - previewLines
+	if visible < 3 {
+		visible = 3
+	}
+
+	switch {
+	case b.err != "" && len(b.sessions) == 0:
+		lines = append(lines, lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Error)).Render("❌ "+b.err))
+	case len(b.sessions) == 0:
+		lines = append(lines, dimStyle.Render("No saved sessions yet. Sessions are saved after every turn."))
+	case len(b.filtered) == 0:
+		lines = append(lines, dimStyle.Render("No sessions match."))
+	default:
+		start := 0
+		if b.cursor >= visible {
+			start = b.cursor - visible + 1
+		}
+		end := min(start+visible, len(b.filtered))
+
+		for i := start; i < end; i++ {
+			row := formatSessionRow(b.filtered[i], b.filtered[i].ID == b.current, width-8)
+			if i == b.cursor {
+				lines = append(lines, activeStyle.Render(row))
+			} else {
+				lines = append(lines, itemStyle.Render(row))
+			}
+		}
+		lines = append(lines, "", dimStyle.Render(fmt.Sprintf("%d of %d sessions", len(b.filtered), len(b.sessions))))
+
+		if len(b.preview) > 0 {
+			lines = append(lines, "")
+			for _, line := range b.preview[:min(len(b.preview), previewLines)] {
+				lines = append(lines, dimStyle.Width(width-8).MaxHeight(1).Render(line))
+			}
+		}
+	}
+
+	var footer string
+	switch {
+	case b.confirmDelete:
+		info, _ := b.Selected()
+		footer = warningStyle.Render(fmt.Sprintf("Delete %q? y to delete • any other key to keep", sessionTitle(info)))
+	case b.renaming:
+		footer = dimStyle.Render("Enter save title • Esc cancel")
+	default:
+		footer = dimStyle.Render("Enter open • Ctrl+R rename • Ctrl+D duplicate • Del delete • Esc close")
+	}
+	lines = append(lines, "", footer)
+
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(theme.Primary)).
+		Padding(1, 2).
+		Width(width)
+
+	return boxStyle.Render(strings.Join(lines, "\n"))
+}
+
+// sessionTitle names a session by its title, or by its first prompt until
+// it has one.
+func sessionTitle(info chat.SessionInfo) string {
+	switch {
+	case info.Title != "":
+		return info.Title
+	case info.Preview != "":
+		return info.Preview
+	}
+	return "Untitled"
+}
+
+// formatSessionRow renders one session with its model, message count and
+// when it was last updated.
+func formatSessionRow(info chat.SessionInfo, current bool, width int) string {
+	marker := "  "
+	if current {
+		marker = "● "
+	}
+
+	details := []string{fmt.Sprintf("%d msgs", info.Messages), formatSessionDate(info.Updated)}
+	if info.Model != "" {
+		details = append([]string{info.Model}, details...)
+	}
+	meta := strings.Join(details, "  ")
+
+	title := sessionTitle(info)
+	room := width - lipgloss.Width(meta) - lipgloss.Width(marker) - 2
+	if runes := []rune(title); room > 1 && len(runes) > room {
+		title = string(runes[:room-1]) + "…"
+	}
+	name := marker + title
+	padding := width - lipgloss.Width(name) - lipgloss.Width(meta)
+	if padding < 2 {
+		padding = 2
+	}
+	return name + strings.Repeat(" ", padding) + meta
+}
+
+// formatSessionDate shows the time for today's sessions and the date for
+// older ones.
+func formatSessionDate(t time.Time) string {
+	now := time.Now()
+	switch {
+	case t.Year() == now.Year() && t.YearDay() == now.YearDay():
+		return "today " + t.Format("15:04")
+	case t.Year() == now.Year():
+		return t.Format("Jan 02 15:04")
+	}
+	return t.Format("2006-01-02")
+}
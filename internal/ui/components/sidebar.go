@@ -2,6 +2,7 @@ package components
 
 import (
 	"Chat2/internal/ui"
+	"fmt"
 	"strings"
 )
 
@@ -11,6 +12,30 @@ type SidebarComponent struct {
 	currentTheme    string
 	availableProviders []string
 	showProviders   bool
+	agent           *AgentStatus
+	title           string
+}
+
+// PlanStep is one step of the agent's plan. Status is "pending",
+// "in_progress" or "done".
+type PlanStep struct {
+	Title  string
+	Status string
+}
+
+// AgentStatus is the agent's progress, shown in place of the controls
+// while agent mode is on.
+type AgentStatus struct {
+	Running   bool
+	Step      int
+	MaxSteps  int
+	Tokens    int
+	MaxTokens int
+	// Activity describes what the agent is doing right now
+	Activity string
+	Plan     []PlanStep
+	// Queued counts the user's steering messages waiting for the next step
+	Queued int
 }
 
 func NewSidebarComponent() *SidebarComponent {
@@ -40,6 +65,18 @@ func (s *SidebarComponent) SetShowProviders(show bool) {
 	s.showProviders = show
 }
 
+// SetAgentStatus shows the agent panel, or the controls again when status
+// is nil.
+func (s *SidebarComponent) SetAgentStatus(status *AgentStatus) {
+	s.agent = status
+}
+
+// SetTitle names the session in the header, which reads "PUKU CHAT" while
+// title is empty.
+func (s *SidebarComponent) SetTitle(title string) {
+	s.title = title
+}
+
 func (s *SidebarComponent) View(width, height int) string {
 	if !s.visible {
 		return ""
@@ -49,7 +86,14 @@ func (s *SidebarComponent) View(width, height int) string {
 	sidebarWidth := 25
 	
 	var content []string
-	content = append(content, styles.SidebarHeader.Render("PUKU CHAT"))
+	header := "PUKU CHAT"
+	if s.title != "" {
+		header = s.title
+		if runes := []rune(header); len(runes) > sidebarWidth-4 {
+			header = string(runes[:sidebarWidth-5]) + "…"
+		}
+	}
+	content = append(content, styles.SidebarHeader.Render(header))
 	content = append(content, "")
 	
 	// Provider section
@@ -72,13 +116,17 @@ func (s *SidebarComponent) View(width, height int) string {
 	content = append(content, styles.SidebarSection.Render("THEME"))
 	content = append(content, styles.SidebarItemActive.Render("→ "+strings.ToUpper(s.currentTheme)))
 	
-	// Controls section
 	content = append(content, "")
-	content = append(content, styles.SidebarSection.Render("CONTROLS"))
-	content = append(content, styles.SidebarItem.Render("Tab - Switch Provider"))
-	content = append(content, styles.SidebarItem.Render("Ctrl+P - Toggle Providers"))
-	content = append(content, styles.SidebarItem.Render("? - Help"))
-	content = append(content, styles.SidebarItem.Render("Esc - Exit"))
+	if s.agent != nil {
+		content = append(content, s.agentSection(sidebarWidth-4)...)
+	} else {
+		// Controls section
+		content = append(content, styles.SidebarSection.Render("CONTROLS"))
+		content = append(content, styles.SidebarItem.Render("Tab - Switch Provider"))
+		content = append(content, styles.SidebarItem.Render("Ctrl+P - Toggle Providers"))
+		content = append(content, styles.SidebarItem.Render("? - Help"))
+		content = append(content, styles.SidebarItem.Render("Esc - Exit"))
+	}
 	
 	// Pad content to fill height
 	for len(content) < height-2 {
@@ -91,4 +139,62 @@ func (s *SidebarComponent) View(width, height int) string {
 		Width(sidebarWidth).
 		Height(height).
 		Render(sidebar)
-}
\ No newline at end of file
+}
+
+// agentSection renders the agent's budget use, current activity and plan.
+func (s *SidebarComponent) agentSection(width int) []string {
+	styles := ui.GetStyles()
+	agent := s.agent
+
+	content := []string{styles.SidebarSection.Render("AGENT")}
+	if !agent.Running {
+		content = append(content, styles.SidebarItem.Render("Idle - send a task"))
+	} else {
+		content = append(content, styles.SidebarItemActive.Render(fmt.Sprintf("Step %d/%d", agent.Step, agent.MaxSteps)))
+		content = append(content, styles.SidebarItem.Render(fmt.Sprintf("%s/%s tokens", shortCount(agent.Tokens), shortCount(agent.MaxTokens))))
+		if agent.Activity != "" {
+			content = append(content, styles.SidebarItem.Render(truncate(agent.Activity, width)))
+		}
+	}
+
+	if len(agent.Plan) > 0 {
+		content = append(content, "", styles.SidebarSection.Render("PLAN"))
+		for _, step := range agent.Plan {
+			switch step.Status {
+			case "done":
+				content = append(content, styles.SidebarItem.Render(truncate("✓ "+step.Title, width)))
+			case "in_progress":
+				content = append(content, styles.SidebarItemActive.Render(truncate("▶ "+step.Title, width)))
+			default:
+				content = append(content, styles.SidebarItem.Render(truncate("○ "+step.Title, width)))
+			}
+		}
+	}
+
+	if agent.Queued > 0 {
+		content = append(content, "", styles.SidebarItemActive.Render(fmt.Sprintf("📝 %d queued for next step", agent.Queued)))
+	}
+
+	content = append(content, "")
+	if agent.Running {
+		content = append(content, styles.SidebarItem.Render("Enter - Steer"))
+		content = append(content, styles.SidebarItem.Render("Esc - Stop"))
+	} else {
+		content = append(content, styles.SidebarItem.Render("/agent off - Leave"))
+	}
+	return content
+}
+
+func truncate(text string, width int) string {
+	if runes := []rune(text); len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return text
+}
+
+func shortCount(n int) string {
+	if n >= 1000 {
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return fmt.Sprintf("%d", n)
+}
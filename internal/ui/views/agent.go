@@ -0,0 +1,161 @@
+package views
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"Chat2/internal/api"
+	"Chat2/internal/ui/components"
+)
+
+const (
+	// defaultAgentSteps and defaultAgentTokens bound an agent task when the
+	// config file sets no budget.
+	defaultAgentSteps  = 25
+	defaultAgentTokens = 200000
+
+	// planToolName is the tool the agent uses to publish its plan.
+	planToolName = "update_plan"
+)
+
+// agentPrompt tells the model how to work in agent mode.
+const agentPrompt = `You are working in agent mode on the user's project. Work step by step until the task is done:
+- Start by calling update_plan with a short list of steps, and call it again whenever a step starts or finishes.
+- Use the available tools to inspect files, make changes and run commands instead of asking the user to do it.
+- Check your work, for example by building or running tests.
+- When the task is complete, reply with a brief summary of what you did.
+The user may add instructions between steps; follow them.`
+
+// planTool lets the model publish its plan to the agent panel.
+var planTool = api.ToolDefinition{
+	Name:        planToolName,
+	Description: "Replace the task plan shown to the user. Send every step with its status each time.",
+	Parameters: json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"steps": {
+				"type": "array",
+				"items": {
+					"type": "object",
+					"properties": {
+						"title": {"type": "string"},
+						"status": {"type": "string", "enum": ["pending", "in_progress", "done"]}
+					},
+					"required": ["title", "status"]
+				}
+			}
+		},
+		"required": ["steps"]
+	}`),
+}
+
+func (m *MainView) AgentMode() bool {
+	return m.agentMode
+}
+
+func (m *MainView) SetAgentMode(on bool) {
+	m.agentMode = on
+	m.agentPlan = nil
+	if on {
+		m.sidebar.SetVisible(true)
+	}
+}
+
+// agentRequest adds the agent instructions and plan tool to req.
+func (m *MainView) agentRequest(req api.ChatRequest) api.ChatRequest {
+	req.Messages = append([]api.ChatMessage{{Role: api.RoleSystem, Content: agentPrompt}}, req.Messages...)
+	req.Tools = append(req.Tools, planTool)
+	return req
+}
+
+// applyPlan replaces the plan shown in the agent panel with the one in the
+// arguments of an update_plan call.
+func (m *MainView) applyPlan(arguments string) (string, error) {
+	var in struct {
+		Steps []struct {
+			Title  string `json:"title"`
+			Status string `json:"status"`
+		} `json:"steps"`
+	}
+	if err := json.Unmarshal([]byte(arguments), &in); err != nil {
+		return "", fmt.Errorf("invalid plan: %w", err)
+	}
+
+	plan := make([]components.PlanStep, 0, len(in.Steps))
+	done := 0
+	for _, step := range in.Steps {
+		plan = append(plan, components.PlanStep{Title: step.Title, Status: step.Status})
+		if step.Status == "done" {
+			done++
+		}
+	}
+	m.agentPlan = plan
+	return fmt.Sprintf("Plan updated: %d of %d steps done.", done, len(plan)), nil
+}
+
+// stepLimit returns why the turn must end before another model round, or
+// an empty string if it may go on.
+func (m *MainView) stepLimit() string {
+	if !m.agentMode {
+		if m.toolRounds >= maxToolRounds {
+			return fmt.Sprintf("Stopped after %d rounds of tool calls.", maxToolRounds)
+		}
+		return ""
+	}
+
+	if m.toolRounds >= m.agentMaxSteps {
+		return fmt.Sprintf("🤖 Agent stopped: the budget of %d steps is used up. Send a message to continue.", m.agentMaxSteps)
+	}
+	if m.agentTokens >= m.agentMaxTokens {
+		return fmt.Sprintf("🤖 Agent stopped: the budget of %s tokens is used up. Send a message to continue.", formatTokens(m.agentMaxTokens))
+	}
+	return ""
+}
+
+// queueSteering keeps a message the user sent while the agent works, to be
+// added before the next step.
+func (m *MainView) queueSteering(message string) {
+	m.steering = append(m.steering, message)
+}
+
+// flushSteering adds the queued steering messages to the conversation.
+func (m *MainView) flushSteering() {
+	for _, message := range m.steering {
+		m.session.AddUserMessage(message)
+	}
+	m.steering = nil
+}
+
+// restoreSteering puts queued messages back into the input when the agent
+// is stopped before it could read them.
+func (m *MainView) restoreSteering() {
+	if len(m.steering) == 0 {
+		return
+	}
+	m.input.SetValue(strings.Join(m.steering, "\n"))
+	m.steering = nil
+}
+
+// agentStatus describes the agent for the sidebar panel, or nil outside
+// agent mode.
+func (m *MainView) agentStatus() *components.AgentStatus {
+	if !m.agentMode {
+		return nil
+	}
+
+	activity := m.streamStatus
+	if activity == "" && m.streaming {
+		activity = "✍ Thinking…"
+	}
+	return &components.AgentStatus{
+		Running:   m.streaming,
+		Step:      m.toolRounds + 1,
+		MaxSteps:  m.agentMaxSteps,
+		Tokens:    m.agentTokens,
+		MaxTokens: m.agentMaxTokens,
+		Activity:  activity,
+		Plan:      m.agentPlan,
+		Queued:    len(m.steering),
+	}
+}
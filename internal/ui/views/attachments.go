@@ -0,0 +1,151 @@
+package views
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"Chat2/internal/api"
+	"Chat2/internal/chat"
+	"Chat2/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// AttachFile adds the file at path to the next message. Images are refused
+// when the current model cannot view them.
+func (m *MainView) AttachFile(path string) {
+	attachment, err := chat.LoadAttachment(path)
+	if err != nil {
+		m.session.AddErrorMessage("Cannot attach file: " + err.Error())
+		return
+	}
+	if attachment.IsImage() && !api.SupportsVision(m.currentTarget()) {
+		m.session.AddErrorMessage(m.visionError(attachment.Name))
+		return
+	}
+
+	for i, existing := range m.attachments {
+		if existing.Path == attachment.Path {
+			m.attachments[i] = attachment
+			return
+		}
+	}
+	m.attachments = append(m.attachments, attachment)
+}
+
+// visionError explains that the current model cannot take the image name.
+func (m *MainView) visionError(name string) string {
+	return "Cannot attach " + name + ": " + strings.ToUpper(m.currentProvider) + " · " + m.currentModel() +
+		" does not accept images. Pick a vision model with /model first."
+}
+
+// checkAttachments returns an error message if the pending attachments
+// cannot be sent to the current model, which may have changed since they
+// were attached.
+func (m *MainView) checkAttachments() string {
+	for _, attachment := range m.attachments {
+		if attachment.IsImage() && !api.SupportsVision(m.currentTarget()) {
+			return m.visionError(attachment.Name)
+		}
+	}
+	return ""
+}
+
+// takeAttachments returns the pending attachments and clears them.
+func (m *MainView) takeAttachments() []types.Attachment {
+	attachments := m.attachments
+	m.attachments = nil
+	return attachments
+}
+
+// removeLastAttachment drops the most recent attachment, reporting whether
+// there was one.
+func (m *MainView) removeLastAttachment() bool {
+	if len(m.attachments) == 0 {
+		return false
+	}
+	m.attachments = m.attachments[:len(m.attachments)-1]
+	return true
+}
+
+// attachmentLabel describes an attachment in a chip.
+func attachmentLabel(attachment types.Attachment) string {
+	icon := "📄"
+	if attachment.IsImage() {
+		icon = "🖼"
+	}
+	return icon + " " + attachment.Name + " · " + chat.FormatSize(int64(len(attachment.Data)))
+}
+
+// loadFileBrowser lists the entries of path for the file browser,
+// directories first, with a parent entry unless path is the root.
+func (m *MainView) loadFileBrowser(path string) {
+	m.fileBrowserPath = path
+	m.fileBrowserSelected = 0
+	m.fileBrowserItems = nil
+	m.fileBrowserErr = ""
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		m.fileBrowserErr = err.Error()
+	}
+
+	var dirs, files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, entry.Name()+"/")
+		} else {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(dirs)
+	sort.Strings(files)
+
+	if filepath.Dir(path) != path {
+		m.fileBrowserItems = append(m.fileBrowserItems, "../")
+	}
+	m.fileBrowserItems = append(m.fileBrowserItems, dirs...)
+	m.fileBrowserItems = append(m.fileBrowserItems, files...)
+}
+
+// openFileBrowserItem enters the selected directory, or attaches the
+// selected file and closes the browser.
+func (m *MainView) openFileBrowserItem() {
+	if len(m.fileBrowserItems) == 0 {
+		return
+	}
+	item := m.fileBrowserItems[m.fileBrowserSelected]
+
+	if item == "../" {
+		m.loadFileBrowser(filepath.Dir(m.fileBrowserPath))
+		return
+	}
+	path := filepath.Join(m.fileBrowserPath, item)
+	if strings.HasSuffix(item, "/") {
+		m.loadFileBrowser(path)
+		return
+	}
+
+	m.state = m.previousState
+	m.AttachFile(path)
+}
+
+func (m *MainView) handleFileBrowserKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyUp:
+		if m.fileBrowserSelected > 0 {
+			m.fileBrowserSelected--
+		}
+	case tea.KeyDown:
+		if m.fileBrowserSelected < len(m.fileBrowserItems)-1 {
+			m.fileBrowserSelected++
+		}
+	case tea.KeyEnter:
+		m.openFileBrowserItem()
+	case tea.KeyBackspace, tea.KeyLeft:
+		m.loadFileBrowser(filepath.Dir(m.fileBrowserPath))
+	}
+	return m, nil
+}
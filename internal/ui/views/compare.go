@@ -0,0 +1,502 @@
+package views
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"Chat2/internal/api"
+	"Chat2/internal/chat"
+	"Chat2/internal/themes"
+	"Chat2/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// A prompt is compared across two to four models; more columns do not fit
+// next to each other.
+const (
+	minCompareTargets = 2
+	maxCompareTargets = 4
+)
+
+// compareColumn is one model's reply in a comparison.
+type compareColumn struct {
+	target    string
+	content   string
+	reasoning string
+	status    string
+	err       string
+	truncated bool
+	stopped   bool
+	usage     *types.TokenUsage
+	// schema names the JSON schema the reply matched in JSON mode;
+	// invalid tells why it did not
+	schema  string
+	invalid string
+	// firstToken and elapsed measure the latency from sending the prompt
+	// to the first token and to the end of the reply
+	started    time.Time
+	firstToken time.Duration
+	elapsed    time.Duration
+	done       bool
+}
+
+// comparison is a prompt sent to several models at once whose replies wait
+// for the user to keep one.
+type comparison struct {
+	prompt   string
+	columns  []compareColumn
+	cancel   context.CancelFunc
+	selected int
+	scroll   int
+	// promptTokens estimates the prompt of every reply, for the usage of
+	// replies that stop before reporting theirs
+	promptTokens int
+	// notice tells why the last reply picked could not be kept
+	notice string
+}
+
+// running reports whether any reply is still streaming.
+func (c *comparison) running() bool {
+	for _, col := range c.columns {
+		if !col.done {
+			return true
+		}
+	}
+	return false
+}
+
+// spent returns the usage of every reply. Replies that were stopped or
+// failed after sending text never report their usage, so it is estimated.
+func (c *comparison) spent() types.TokenUsage {
+	var spent types.TokenUsage
+	for _, col := range c.columns {
+		switch {
+		case col.usage != nil:
+			spent.Add(*col.usage)
+		case col.content != "" || col.reasoning != "":
+			spent.Add(priceUsage(col.target, api.Usage{
+				PromptTokens:     c.promptTokens,
+				CompletionTokens: chat.EstimateTokens(col.content + col.reasoning),
+			}))
+		}
+	}
+	return spent
+}
+
+// compareEventMsg delivers the next event of one column's reply. id tells
+// events of a discarded comparison apart from the current one.
+type compareEventMsg struct {
+	id     int
+	column int
+	event  api.StreamEvent
+	events <-chan api.StreamEvent
+	closed bool
+}
+
+func waitForComparison(id, column int, events <-chan api.StreamEvent) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-events
+		return compareEventMsg{id: id, column: column, event: event, events: events, closed: !ok}
+	}
+}
+
+// SetCompareTargets sends every following prompt to each of targets, which
+// are provider IDs or "provider/model" pairs.
+func (m *MainView) SetCompareTargets(targets []string) {
+	if len(targets) < minCompareTargets || len(targets) > maxCompareTargets {
+		m.session.AddErrorMessage(fmt.Sprintf("Compare %d to %d models, e.g. /compare openai anthropic/claude-sonnet-4-5", minCompareTargets, maxCompareTargets))
+		return
+	}
+
+	resolved := make([]string, 0, len(targets))
+	names := make([]string, 0, len(targets))
+	for _, target := range targets {
+		id, model := api.SplitTarget(target)
+		if _, ok := api.GetProvider(id, nil); !ok {
+			m.session.AddErrorMessage("Unknown provider: " + id)
+			return
+		}
+		available := ""
+		for _, candidate := range m.availableProviders {
+			if candidateID, _ := api.SplitTarget(candidate); candidateID == id {
+				available = candidate
+				break
+			}
+		}
+		if available == "" {
+			m.session.AddErrorMessage(strings.ToUpper(id) + " is not set up. Set its API key or start its server first.")
+			return
+		}
+		if model == "" {
+			model = m.selectedModels[id]
+		}
+		if model == "" {
+			model = api.DefaultModel(available)
+		}
+		resolved = append(resolved, id+"/"+model)
+		names = append(names, strings.ToUpper(id)+" · "+model)
+	}
+
+	m.compareTargets = resolved
+	m.session.AddMessage(types.NewSuccess(fmt.Sprintf("⚖️ Compare mode on: each prompt goes to %s. /compare off to leave.", strings.Join(names, ", "))))
+}
+
+func (m *MainView) ClearCompareTargets() {
+	if m.compareTargets == nil {
+		m.session.AddNotice("Compare mode is not on.")
+		return
+	}
+	m.compareTargets = nil
+	m.session.AddMessage(types.NewSuccess("💬 Compare mode off."))
+}
+
+// startComparison sends prompt with the conversation so far to every
+// compare target at once. Tools are not offered, since each model would
+// run them in the same workspace.
+func (m *MainView) startComparison(prompt string) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.compareID++
+	m.comparison = &comparison{prompt: prompt, cancel: cancel}
+
+	history := append(m.session.History(m.contextBudget), api.ChatMessage{Role: api.RoleUser, Content: prompt})
+	for _, msg := range history {
+		m.comparison.promptTokens += chat.EstimateTokens(msg.Content)
+	}
+	cmds := make([]tea.Cmd, 0, len(m.compareTargets))
+	for i, target := range m.compareTargets {
+		req := api.ChatRequest{
+			Messages:       history,
+			Params:         m.paramsFor(target),
+			ResponseFormat: m.responseFormat(),
+		}
+		m.comparison.columns = append(m.comparison.columns, compareColumn{target: target, started: time.Now()})
+		cmds = append(cmds, waitForComparison(m.compareID, i, api.StreamTarget(ctx, target, req, m.apiKeys)))
+	}
+
+	m.previousState = m.state
+	m.state = types.StateCompare
+	return tea.Batch(cmds...)
+}
+
+// handleCompareEvent applies a stream event to its column and waits for the
+// next one until that reply ends.
+func (m *MainView) handleCompareEvent(msg compareEventMsg) tea.Cmd {
+	if m.comparison == nil || msg.id != m.compareID {
+		return nil
+	}
+	col := &m.comparison.columns[msg.column]
+	if col.done {
+		return nil
+	}
+
+	if msg.closed {
+		col.done = true
+		col.elapsed = time.Since(col.started)
+		return nil
+	}
+
+	switch msg.event.Type {
+	case api.EventRetry:
+		col.status = fmt.Sprintf("🔁 Retrying (%d/%d)…", msg.event.Attempt, msg.event.MaxAttempts)
+	case api.EventDelta, api.EventReasoning:
+		if col.firstToken == 0 {
+			col.firstToken = time.Since(col.started)
+		}
+		col.status = ""
+		if msg.event.Type == api.EventDelta {
+			col.content += msg.event.Text
+		} else {
+			col.reasoning += msg.event.Text
+		}
+	case api.EventTruncated:
+		col.truncated = true
+	case api.EventUsage:
+		usage := priceUsage(col.target, *msg.event.Usage)
+		col.usage = &usage
+	case api.EventDone, api.EventError:
+		if msg.event.Err != nil {
+			col.err = msg.event.Err.Error()
+		} else if m.jsonSchema != nil {
+			m.checkComparedReply(col)
+		}
+		col.done = true
+		col.elapsed = time.Since(col.started)
+		return nil
+	}
+
+	return waitForComparison(msg.id, msg.column, msg.events)
+}
+
+// checkComparedReply validates a finished reply in JSON mode, like a reply
+// in the chat but without asking the model to correct it.
+func (m *MainView) checkComparedReply(col *compareColumn) {
+	reply, err := m.checkStructuredReply(col.content)
+	if err != nil {
+		col.invalid = err.Error()
+		return
+	}
+	col.content = reply
+	col.schema = m.jsonSchema.Name
+}
+
+// stopComparison cancels the replies still streaming and keeps what they
+// sent so far.
+func (m *MainView) stopComparison() {
+	m.comparison.cancel()
+	for i := range m.comparison.columns {
+		col := &m.comparison.columns[i]
+		if !col.done {
+			col.done = true
+			col.elapsed = time.Since(col.started)
+			col.stopped = true
+		}
+	}
+}
+
+// keepComparedReply ends the comparison with the reply of column as the
+// assistant's turn. The usage of every reply counts towards the session. In
+// JSON mode only replies that match the schema can be kept.
+func (m *MainView) keepComparedReply(column int) tea.Cmd {
+	c := m.comparison
+	col := c.columns[column]
+	name := fmt.Sprintf("%d · %s", column+1, api.DefaultModel(col.target))
+	if col.content == "" {
+		switch {
+		case col.err != "":
+			c.notice = name + " failed; keep another reply"
+		case col.done:
+			c.notice = name + " sent an empty reply; keep another one"
+		default:
+			c.notice = name + " has not replied yet"
+		}
+		return nil
+	}
+	if m.jsonSchema != nil && col.schema == "" {
+		switch {
+		case !col.done:
+			c.notice = name + " has not finished yet"
+		case col.invalid != "":
+			c.notice = name + " does not match the " + m.jsonSchema.Name + " schema; keep another reply"
+		default:
+			c.notice = name + " did not finish its JSON; keep another reply"
+		}
+		return nil
+	}
+	c.cancel()
+	spent := c.spent()
+
+	var others []string
+	for i, other := range c.columns {
+		if i != column {
+			others = append(others, api.DefaultModel(other.target))
+		}
+	}
+
+	id, model := api.SplitTarget(col.target)
+	kept := types.Message{
+		Content:   col.content,
+		Reasoning: col.reasoning,
+		Provider:  id,
+		Model:     model,
+		Usage:     col.usage,
+		Truncated: col.truncated,
+		Schema:    col.schema,
+	}
+	if col.stopped || !col.done || col.err != "" {
+		kept.Status = types.StatusInterrupted
+	}
+	m.session.AddComparison(c.prompt, kept, spent)
+	m.session.AddNotice(fmt.Sprintf("⚖️ Kept %s over %s.", model, strings.Join(others, ", ")))
+	m.saveSession()
+
+	m.comparison = nil
+	m.state = m.previousState
+	m.enterChat()
+	return m.requestTitle()
+}
+
+// discardComparison drops the replies and puts the prompt back in the input.
+// What they cost still counts towards the session.
+func (m *MainView) discardComparison() {
+	m.comparison.cancel()
+	if spent := m.comparison.spent(); spent.TotalTokens() > 0 {
+		m.session.Usage.Add(spent)
+		m.saveSession()
+	}
+	m.input.SetValue(m.comparison.prompt)
+	m.comparison = nil
+	m.state = m.previousState
+}
+
+func (m *MainView) handleCompareKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.comparison
+	c.notice = ""
+	switch msg.Type {
+	case tea.KeyEsc:
+		if c.running() {
+			m.stopComparison()
+		} else {
+			m.discardComparison()
+		}
+	case tea.KeyLeft:
+		c.selected = (c.selected + len(c.columns) - 1) % len(c.columns)
+	case tea.KeyRight, tea.KeyTab:
+		c.selected = (c.selected + 1) % len(c.columns)
+	case tea.KeyUp:
+		if c.scroll > 0 {
+			c.scroll--
+		}
+	case tea.KeyDown:
+		c.scroll++
+	case tea.KeyPgUp:
+		c.scroll = max(0, c.scroll-10)
+	case tea.KeyPgDown:
+		c.scroll += 10
+	case tea.KeyEnter:
+		return m, m.keepComparedReply(c.selected)
+	case tea.KeyRunes:
+		if len(msg.Runes) == 1 && msg.Runes[0] >= '1' && int(msg.Runes[0]-'1') < len(c.columns) {
+			return m, m.keepComparedReply(int(msg.Runes[0] - '1'))
+		}
+	}
+	return m, nil
+}
+
+// renderCompareView shows the replies of a comparison side by side, each
+// with its latency, tokens and cost, and how to keep one.
+func (m *MainView) renderCompareView(containerWidth int) string {
+	theme := themes.GetCurrentTheme()
+	c := m.comparison
+	if c == nil {
+		return ""
+	}
+
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.DimText))
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(theme.Text)).
+		Render(fmt.Sprintf("⚖️ Comparing %d models", len(c.columns)))
+	prompt := dimStyle.Width(containerWidth - 4).MaxHeight(2).Render("› " + c.prompt)
+
+	columnWidth := (containerWidth-4)/len(c.columns) - 3
+	bodyHeight := max(m.height-18, 3)
+	var columns []string
+	for i, col := range c.columns {
+		columns = append(columns, m.renderCompareColumn(i, col, columnWidth, bodyHeight))
+	}
+
+	keys := "1–" + fmt.Sprint(len(c.columns)) + " or ← → Enter to keep a reply • ↑ ↓ scroll • ESC "
+	if c.running() {
+		keys += "stop"
+	} else {
+		keys += "discard"
+	}
+	footer := dimStyle.Width(containerWidth - 4).Align(lipgloss.Center).Render(keys)
+	if c.notice != "" {
+		footer = lipgloss.NewStyle().
+			Foreground(lipgloss.Color(theme.Warning)).
+			Width(containerWidth - 4).
+			Align(lipgloss.Center).
+			Render("⚠️ " + c.notice)
+	}
+
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		title,
+		prompt,
+		"",
+		lipgloss.JoinHorizontal(lipgloss.Top, columns...),
+		"",
+		footer,
+	)
+	return lipgloss.NewStyle().Width(containerWidth).Padding(1, 2).Render(content)
+}
+
+// renderCompareColumn renders one reply of a comparison. While replies
+// stream it follows their end; afterwards ↑ ↓ scroll them together.
+func (m *MainView) renderCompareColumn(index int, col compareColumn, width, bodyHeight int) string {
+	theme := themes.GetCurrentTheme()
+	c := m.comparison
+	innerWidth := width - 2
+
+	id, model := api.SplitTarget(col.target)
+	header := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(theme.Primary)).
+		Width(innerWidth).
+		MaxHeight(1).
+		Render(fmt.Sprintf("%d · %s · %s", index+1, strings.ToUpper(id), model))
+
+	body := col.content
+	switch {
+	case body != "":
+		if !col.done {
+			body += "▎"
+		}
+	case col.reasoning != "" && !col.done:
+		body = "💭 Thinking…"
+	case !col.done:
+		body = m.getAnimatedIcon() + " Waiting for the first token…"
+	}
+	lines := strings.Split(lipgloss.NewStyle().Width(innerWidth).Render(body), "\n")
+	start := max(len(lines)-bodyHeight, 0)
+	if !c.running() {
+		start = min(c.scroll, start)
+	}
+	lines = lines[start:min(start+bodyHeight, len(lines))]
+	bodyText := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Text)).
+		Width(innerWidth).
+		Height(bodyHeight).
+		Render(strings.Join(lines, "\n"))
+
+	statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.DimText)).Width(innerWidth).MaxHeight(1)
+	var status string
+	switch {
+	case col.err != "":
+		status = statusStyle.Foreground(lipgloss.Color(theme.Error)).Render("❌ " + col.err)
+	case !col.done && col.status != "":
+		status = statusStyle.Render(col.status)
+	case !col.done:
+		status = statusStyle.Render(fmt.Sprintf("⏳ %.1fs", time.Since(col.started).Seconds()))
+	case col.invalid != "":
+		status = statusStyle.Foreground(lipgloss.Color(theme.Error)).Render("🧾 " + col.invalid)
+	default:
+		timing := fmt.Sprintf("⏱ %.1fs first token · %.1fs total", col.firstToken.Seconds(), col.elapsed.Seconds())
+		if col.stopped {
+			timing = fmt.Sprintf("⏹ Stopped after %.1fs", col.elapsed.Seconds())
+		}
+		status = statusStyle.Render(timing)
+	}
+
+	usage := "–"
+	if col.usage != nil {
+		usage = fmt.Sprintf("↑%s ↓%s", formatTokens(col.usage.PromptTokens), formatTokens(col.usage.CompletionTokens))
+		if col.usage.Priced {
+			usage += " · " + formatCost(col.usage.Cost)
+		}
+	}
+	if col.truncated {
+		usage += " · ✂ cut off"
+	}
+	if col.schema != "" {
+		usage += " · 🧾 valid JSON"
+	}
+	usageLine := statusStyle.Render(usage)
+
+	border := theme.Border
+	if index == c.selected {
+		border = theme.Primary
+	}
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(border)).
+		Padding(0, 1).
+		Width(width).
+		MarginRight(1).
+		Render(lipgloss.JoinVertical(lipgloss.Left, header, "", bodyText, "", status, usageLine))
+}
@@ -3,13 +3,34 @@ package views
 import (
 	"strings"
 
-	"Chat2/internal/api"
 	"Chat2/internal/types"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 func (m *MainView) handleKeyInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// A tool call waiting for approval takes every key but Ctrl+C
+	if m.state == types.StateToolConfirm && msg.Type != tea.KeyCtrlC {
+		return m.handleToolConfirmKeys(msg)
+	}
+
+	// A comparison takes every key but Ctrl+C until a reply is kept
+	if m.state == types.StateCompare && msg.Type != tea.KeyCtrlC {
+		return m.handleCompareKeys(msg)
+	}
+
+	// The session browser edits titles and filters inline, so it takes
+	// every key but Ctrl+C too
+	if m.state == types.StateSessions && msg.Type != tea.KeyCtrlC {
+		return m.handleSessionBrowserKeys(msg)
+	}
+
+	// Esc or Ctrl+X stops a reply that is still streaming
+	if m.streaming && (msg.Type == tea.KeyEsc || msg.Type == tea.KeyCtrlX) {
+		m.stopStreaming()
+		return m, nil
+	}
+
 	// Global key handlers that work in all states
 	switch msg.Type {
 	case tea.KeyCtrlC:
@@ -17,7 +38,7 @@ func (m *MainView) handleKeyInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case tea.KeyEsc:
 		// Escape key: return to previous state if in help/file browser
-		if m.state == types.StateHelp || m.state == types.StateFileBrowser {
+		if m.state == types.StateHelp || m.state == types.StateFileBrowser || m.state == types.StateModelPicker {
 			m.state = m.previousState
 			return m, nil
 		}
@@ -36,7 +57,7 @@ func (m *MainView) handleKeyInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if len(msg.Runes) > 0 && msg.Runes[0] == '?' {
 			// Only show help if the input field is empty before typing '?'
 			inputValue := strings.TrimSpace(m.input.Value())
-			if inputValue == "" && m.state != types.StateHelp {
+			if inputValue == "" && m.state != types.StateHelp && m.state != types.StateModelPicker {
 				m.previousState = m.state
 				m.state = types.StateHelp
 				m.input.SetValue("") // Clear the '?' character
@@ -53,6 +74,8 @@ func (m *MainView) handleKeyInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleFileBrowserKeys(msg)
 	case types.StateExitConfirm:
 		return m.handleExitConfirmKeys(msg)
+	case types.StateModelPicker:
+		return m.handleModelPickerKeys(msg)
 	default:
 		return m.handleDefaultKeys(msg)
 	}
@@ -61,16 +84,47 @@ func (m *MainView) handleKeyInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 func (m *MainView) handleDefaultKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.Type {
 	case tea.KeyTab:
-		if len(m.availableProviders) > 1 {
+		// The provider stays put until the reply in progress ends
+		if len(m.availableProviders) > 1 && !m.streaming {
 			return m, m.SwitchProvider()
 		}
 
+	case tea.KeyCtrlO:
+		m.expandTools = !m.expandTools
+		return m, nil
+
+	case tea.KeyCtrlT:
+		m.expandThinking = !m.expandThinking
+		return m, nil
+
+	case tea.KeyCtrlN:
+		// Continue a reply that was cut off at the token limit
+		if cmd := m.continueReply(); cmd != nil {
+			return m, cmd
+		}
+
+	case tea.KeyBackspace:
+		// Backspace in an empty input removes the last attachment
+		if m.input.Value() == "" && m.removeLastAttachment() {
+			return m, nil
+		}
+
 	case tea.KeyCtrlP:
 		m.showProviders = !m.showProviders
 		m.sidebar.SetShowProviders(m.showProviders)
 		return m, nil
 
 	case tea.KeyEnter:
+		// While the agent works, a message steers it at its next step
+		if m.agentMode && m.streaming {
+			message := strings.TrimSpace(m.input.Value())
+			if message != "" && !strings.HasPrefix(message, "/") {
+				m.queueSteering(message)
+				m.input.SetValue("")
+			}
+			return m, nil
+		}
+
 		if !m.loading && !m.streaming && strings.TrimSpace(m.input.Value()) != "" {
 			message := strings.TrimSpace(m.input.Value())
 
@@ -80,24 +134,29 @@ func (m *MainView) handleDefaultKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 
 			if len(m.availableProviders) == 0 {
-				m.session.AddMessage("❌ No AI provider configured. Please set up API keys.")
+				m.session.AddMessage(types.NewError("No AI provider configured. Please set up API keys."))
 				return m, nil
 			}
 
-			m.session.AddUserMessage(message)
-			m.input.SetValue("")
-			m.streaming = true
-			m.currentResponse.Reset()
-
-			// Transition to active state on first message
-			if m.state == types.StateLanding {
-				m.state = types.StateChat
-				m.showCommands = false
-				m.showSidebar = true
-				m.sidebar.SetVisible(true)
+			if problem := m.checkAttachments(); problem != "" {
+				m.session.AddErrorMessage(problem)
+				return m, nil
 			}
 
-			return m, api.SendToAI(message, m.currentProvider, m.apiKeys)
+			if m.compareTargets != nil {
+				if len(m.attachments) > 0 {
+					m.session.AddErrorMessage("Attachments cannot be compared. Remove them with Backspace or leave compare mode with /compare off.")
+					return m, nil
+				}
+				m.input.SetValue("")
+				return m, m.startComparison(message)
+			}
+
+			m.session.AddUserMessage(message, m.takeAttachments()...)
+			m.input.SetValue("")
+			m.enterChat()
+
+			return m, m.startStream()
 		}
 	}
 
@@ -107,20 +166,21 @@ func (m *MainView) handleDefaultKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func (m *MainView) handleHelpKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	// In help mode, most keys just return to previous state
-	switch msg.Type {
-	case tea.KeyEnter, tea.KeySpace:
-		m.state = m.previousState
-		return m, nil
+// enterChat switches from the landing page to the chat once the first
+// message is sent.
+func (m *MainView) enterChat() {
+	if m.state == types.StateLanding {
+		m.state = types.StateChat
+		m.showCommands = false
+		m.showSidebar = true
+		m.sidebar.SetVisible(true)
 	}
-	return m, nil
 }
 
-func (m *MainView) handleFileBrowserKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
-	// File browser navigation (to be implemented)
+func (m *MainView) handleHelpKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// In help mode, most keys just return to previous state
 	switch msg.Type {
-	case tea.KeyEnter:
+	case tea.KeyEnter, tea.KeySpace:
 		m.state = m.previousState
 		return m, nil
 	}
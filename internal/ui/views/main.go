@@ -1,13 +1,19 @@
 package views
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"Chat2/internal/api"
 	"Chat2/internal/chat"
 	"Chat2/internal/commands"
+	"Chat2/internal/config"
+	"Chat2/internal/schema"
+	"Chat2/internal/tools"
 	"Chat2/internal/types"
 	"Chat2/internal/ui/components"
 
@@ -16,22 +22,71 @@ import (
 
 type MainView struct {
 	// Core components
-	input    *components.InputComponent
-	sidebar  *components.SidebarComponent
-	session  *chat.Session
-	commands *commands.Registry
+	input          *components.InputComponent
+	sidebar        *components.SidebarComponent
+	modelPicker    *components.ModelPickerComponent
+	sessionBrowser *components.SessionBrowserComponent
+	session        *chat.Session
+	commands       *commands.Registry
+
+	// store saves the session after every turn; saveErr is the last
+	// failure reported
+	store   *chat.Store
+	saveErr string
+	// pendingTarget is the model of a resumed session that waits for local
+	// model discovery, which localModelsLoaded tells has finished
+	pendingTarget     *resumeTarget
+	localModelsLoaded bool
+
+	// titleModel names sessions, the current model when empty;
+	// titleRequested is the last session a title was asked for
+	titleModel     string
+	titleRequested string
 
 	// State
 	state           types.State
 	previousState   types.State
 	loading         bool
 	streaming       bool
-	currentResponse strings.Builder
+	streamCtx       context.Context
+	cancelStream    context.CancelFunc
+	streamID        int
+	streamTarget    string
+	streamStatus    string
+
+	// Tool calling
+	tools        *tools.Registry
+	pendingTools []types.ToolCall
+	toolRounds   int
+	expandTools  bool
+
+	// expandThinking shows the whole reasoning of replies instead of a
+	// summary line
+	expandThinking bool
+
+	// Agent mode: the budget of a task, the tokens it used so far, the
+	// plan published by the model and messages queued to steer it
+	agentMode      bool
+	agentMaxSteps  int
+	agentMaxTokens int
+	agentTokens    int
+	agentPlan      []components.PlanStep
+	steering       []string
+
+	// Tool approval: the call waiting for the user's answer, the selected
+	// option and the tools allowed for the rest of the session
+	pendingApproval  *types.ToolCall
+	approvalSelected int
+	approvedTools    map[string]bool
 
 	// Provider and theme management
 	currentProvider    string
 	availableProviders []string
 	apiKeys            map[string]string
+	contextBudget      int
+	globalParams       api.Params
+	providerParams     map[string]api.Params
+	selectedModels     map[string]string
 	currentTheme       string
 
 	// UI state
@@ -45,23 +100,37 @@ type MainView struct {
 	animatedIconFrame  int
 
 	// File browser state
-	fileBrowserPath  string
-	fileBrowserItems []string
+	fileBrowserPath     string
+	fileBrowserItems    []string
+	fileBrowserSelected int
+	fileBrowserErr      string
+
+	// Files attached to the next message
+	attachments []types.Attachment
+
+	// Structured output: the schema replies must match and how often the
+	// current reply was sent back for correction
+	jsonSchema    *schema.Schema
+	schemaRetries int
+
+	// Compare mode: the targets each prompt goes to and the replies of the
+	// current prompt
+	compareTargets []string
+	comparison     *comparison
+	compareID      int
 
 	// Exit confirmation
 	exitConfirm        bool
 	exitToggleSelected int
 }
 
-func NewMainView(apiKeys map[string]string) *MainView {
-	var availableProviders []string
-	for name := range api.Providers {
-		if key, exists := apiKeys[name]; exists && key != "" {
-			availableProviders = append(availableProviders, name)
-		}
-	}
+func NewMainView(cfg *config.Config, apiKeys map[string]string) *MainView {
+	availableProviders := api.AvailableProviders(apiKeys)
 
-	currentProvider := "openrouter"
+	currentProvider := ""
+	if registered := api.Registered(); len(registered) > 0 {
+		currentProvider = registered[0].ID
+	}
 	if len(availableProviders) > 0 {
 		currentProvider = availableProviders[0]
 	}
@@ -71,11 +140,23 @@ func NewMainView(apiKeys map[string]string) *MainView {
 	mv := &MainView{
 		input:              components.NewInputComponent("Write something that i don't know..."),
 		sidebar:            components.NewSidebarComponent(),
+		modelPicker:        components.NewModelPickerComponent(),
+		sessionBrowser:     components.NewSessionBrowserComponent(),
+		tools:              tools.NewRegistry(),
+		approvedTools:      make(map[string]bool),
 		session:            session,
+		store:              chat.NewStore(filepath.Join(config.DataDir(), "sessions")),
 		state:              types.StateLanding,
 		currentProvider:    currentProvider,
 		availableProviders: availableProviders,
 		apiKeys:            apiKeys,
+		contextBudget:      cfg.ContextTokens,
+		globalParams:       api.ParamsFromConfig(cfg.Params),
+		providerParams:     make(map[string]api.Params),
+		agentMaxSteps:      cfg.Agent.MaxSteps,
+		titleModel:         cfg.TitleModel,
+		agentMaxTokens:     cfg.Agent.MaxTokens,
+		selectedModels:     config.LoadModelChoices(),
 		currentTheme:       "puku",
 		showCommands:       true,
 		showSidebar:        false,
@@ -86,7 +167,26 @@ func NewMainView(apiKeys map[string]string) *MainView {
 		animatedIconFrame:  0,
 	}
 
+	for id, params := range cfg.ProviderParams {
+		mv.providerParams[id] = api.ParamsFromConfig(params)
+	}
+	if mv.agentMaxSteps <= 0 {
+		mv.agentMaxSteps = defaultAgentSteps
+	}
+	if mv.agentMaxTokens <= 0 {
+		mv.agentMaxTokens = defaultAgentTokens
+	}
+
 	mv.commands = commands.NewRegistry(mv)
+	if root, err := os.Getwd(); err == nil {
+		tools.RegisterFileTools(mv.tools, root)
+		tools.RegisterShellTool(mv.tools, root, tools.ShellOptions{
+			Allow:     cfg.Shell.Allow,
+			Deny:      cfg.Shell.Deny,
+			Timeout:   time.Duration(cfg.Shell.TimeoutSeconds) * time.Second,
+			MaxOutput: cfg.Shell.MaxOutputBytes,
+		})
+	}
 	
 	// Configure sidebar
 	mv.sidebar.SetCurrentProvider(currentProvider)
@@ -102,6 +202,9 @@ func (m *MainView) Init() tea.Cmd {
 		tea.Cmd(func() tea.Msg {
 			return types.ConfigLoadedMsg{}
 		}),
+		func() tea.Msg {
+			return types.LocalModelsMsg(api.DiscoverLocalModels())
+		},
 		tea.Tick(time.Millisecond*150, func(time.Time) tea.Msg {
 			return types.AnimationMsg{}
 		}),
@@ -119,30 +222,54 @@ func (m *MainView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case types.ConfigLoadedMsg:
 		if len(m.availableProviders) == 0 {
-			m.session.AddMessage("⚠️  No API keys found. Please set OPENROUTER_API_KEY environment variable.")
+			m.session.AddNotice("⚠️  No API keys found. Please set one of: " + strings.Join(m.keyEnvNames(), ", ") + ", or start a local model server such as Ollama.")
 		} else {
-			m.session.AddMessage(fmt.Sprintf("🎉 Ready! Using %s. Press Tab to switch providers.", strings.ToUpper(m.currentProvider)))
+			m.session.AddMessage(types.NewSuccess(fmt.Sprintf("🎉 Ready! Using %s. Press Tab to switch providers.", strings.ToUpper(m.currentProvider))))
+		}
+		return m, nil
+
+	case types.LocalModelsMsg:
+		m.localModelsLoaded = true
+		defer m.restorePendingTarget()
+		if len(msg) == 0 {
+			return m, nil
+		}
+		hadProviders := len(m.availableProviders) > 0
+		m.availableProviders = append(m.availableProviders, msg...)
+		m.sidebar.SetAvailableProviders(m.availableProviders)
+		m.session.AddMessage(types.NewSuccess(fmt.Sprintf("🏠 Found %d local models. Press Tab to pick one.", len(msg))))
+		if !hadProviders {
+			m.currentProvider = msg[0]
+			m.session.SetProvider(m.currentProvider)
+			m.sidebar.SetCurrentProvider(m.currentProvider)
 		}
 		return m, nil
 
 	case tea.KeyMsg:
 		return m.handleKeyInput(msg)
 
-	case types.StreamCharMsg:
-		m.currentResponse.WriteString(string(msg))
+	case streamEventMsg:
+		return m, m.handleStreamEvent(msg)
+
+	case compareEventMsg:
+		return m, m.handleCompareEvent(msg)
+
+	case titleMsg:
+		m.handleTitle(msg)
 		return m, nil
 
-	case types.StreamEndMsg:
-		if m.currentResponse.Len() > 0 {
-			response := m.currentResponse.String()
-			m.session.AddAIResponse(response)
-		}
-		m.currentResponse.Reset()
-		m.streaming = false
+	case toolResultMsg:
+		return m, m.handleToolResult(msg)
+
+	case toolOutputMsg:
+		return m, m.handleToolOutput(msg)
+
+	case modelCatalogMsg:
+		m.handleModelCatalog(msg)
 		return m, nil
 
 	case types.ResponseMsg:
-		m.session.AddAIResponse(string(msg))
+		m.session.AddAIResponse(string(msg), m.currentProvider, m.currentModel())
 		m.loading = false
 		m.streaming = false
 		return m, nil
@@ -150,11 +277,14 @@ func (m *MainView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case types.ErrorMsg:
 		m.session.AddErrorMessage(string(msg))
 		m.loading = false
-		m.streaming = false
 		return m, nil
 
 	case types.ProviderSetMsg:
+		if m.streaming {
+			return m, nil
+		}
 		m.currentProvider = string(msg)
+		m.pendingTarget = nil
 		m.session.SetProvider(m.currentProvider)
 		m.sidebar.SetCurrentProvider(m.currentProvider)
 		return m, nil
@@ -172,7 +302,7 @@ func (m *MainView) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 // Implement UIModel interface
-func (m *MainView) AddMessage(message string) {
+func (m *MainView) AddMessage(message types.Message) {
 	m.session.AddMessage(message)
 }
 
@@ -180,7 +310,7 @@ func (m *MainView) ClearMessages() {
 	m.session.Clear()
 }
 
-func (m *MainView) GetMessages() []string {
+func (m *MainView) GetMessages() []types.Message {
 	return m.session.GetMessages()
 }
 
@@ -188,6 +318,46 @@ func (m *MainView) GetCurrentProvider() string {
 	return m.currentProvider
 }
 
+// currentModel returns the model used by the current provider: the model
+// pinned by a "provider/model" target, the one picked for the provider, or
+// the provider's default.
+func (m *MainView) currentModel() string {
+	id, pinned := api.SplitTarget(m.currentProvider)
+	if pinned != "" {
+		return pinned
+	}
+	if model := m.selectedModels[id]; model != "" {
+		return model
+	}
+	return api.DefaultModel(id)
+}
+
+// currentTarget returns the "provider/model" pair requests are sent to.
+func (m *MainView) currentTarget() string {
+	id, _ := api.SplitTarget(m.currentProvider)
+	return id + "/" + m.currentModel()
+}
+
+// isOverlayState reports whether a full-screen view replaces the chat.
+func (m *MainView) isOverlayState() bool {
+	switch m.state {
+	case types.StateHelp, types.StateFileBrowser, types.StateExitConfirm, types.StateModelPicker, types.StateToolConfirm, types.StateCompare, types.StateSessions:
+		return true
+	}
+	return false
+}
+
+// keyEnvNames lists the environment variables that enable a provider.
+func (m *MainView) keyEnvNames() []string {
+	var names []string
+	for _, reg := range api.Registered() {
+		if reg.KeyEnv != "" {
+			names = append(names, reg.KeyEnv)
+		}
+	}
+	return names
+}
+
 func (m *MainView) GetAvailableProviders() []string {
 	return m.availableProviders
 }
@@ -243,5 +413,5 @@ func (m *MainView) GetFileBrowserPath() string {
 }
 
 func (m *MainView) SetFileBrowserPath(path string) {
-	m.fileBrowserPath = path
+	m.loadFileBrowser(path)
 }
\ No newline at end of file
@@ -0,0 +1,90 @@
+package views
+
+import (
+	"strings"
+
+	"Chat2/internal/api"
+	"Chat2/internal/config"
+	"Chat2/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// modelCatalogMsg carries a provider's model listing to the picker.
+type modelCatalogMsg struct {
+	provider string
+	models   []api.ModelInfo
+	err      error
+}
+
+func loadModelCatalog(target string, apiKeys map[string]string, refresh bool) tea.Cmd {
+	return func() tea.Msg {
+		models, err := api.LoadModels(target, apiKeys, refresh)
+		return modelCatalogMsg{provider: target, models: models, err: err}
+	}
+}
+
+// OpenModelPicker shows the model picker for the current provider and starts
+// loading its catalog.
+func (m *MainView) OpenModelPicker() tea.Cmd {
+	if m.state != types.StateModelPicker {
+		m.previousState = m.state
+	}
+	m.state = types.StateModelPicker
+
+	id, _ := api.SplitTarget(m.currentProvider)
+	return tea.Batch(
+		m.modelPicker.Open(id, m.currentModel()),
+		loadModelCatalog(id, m.apiKeys, false),
+	)
+}
+
+// SelectModel switches the current provider to model and remembers the choice
+// for the provider. Discovered local targets pin their model in the target
+// itself, so for those the target is replaced instead.
+func (m *MainView) SelectModel(model string) {
+	model = strings.TrimSpace(model)
+	if model == "" {
+		return
+	}
+
+	id, pinned := api.SplitTarget(m.currentProvider)
+	if pinned != "" {
+		m.currentProvider = id + "/" + model
+		m.sidebar.SetCurrentProvider(m.currentProvider)
+	} else {
+		m.selectedModels[id] = model
+		if err := config.SaveModelChoice(id, model); err != nil {
+			m.session.AddErrorMessage("Could not save model choice: " + err.Error())
+		}
+	}
+	m.session.AddMessage(types.NewSuccess("🧠 Switched " + strings.ToUpper(id) + " to " + model))
+}
+
+func (m *MainView) handleModelCatalog(msg modelCatalogMsg) {
+	if m.state != types.StateModelPicker || msg.provider != m.modelPicker.Provider() {
+		return
+	}
+	m.modelPicker.SetModels(msg.models, msg.err)
+}
+
+func (m *MainView) handleModelPickerKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		if model, ok := m.modelPicker.Selected(); ok {
+			m.state = m.previousState
+			m.SelectModel(model)
+		}
+		return m, nil
+	case tea.KeyCtrlR:
+		provider := m.modelPicker.Provider()
+		return m, tea.Batch(
+			m.modelPicker.Open(provider, m.currentModel()),
+			loadModelCatalog(provider, m.apiKeys, true),
+		)
+	}
+
+	var cmd tea.Cmd
+	m.modelPicker, cmd = m.modelPicker.Update(msg)
+	return m, cmd
+}
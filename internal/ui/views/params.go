@@ -0,0 +1,76 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"Chat2/internal/api"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// continuePrompt asks the model to go on with a reply that was cut off.
+const continuePrompt = "Continue exactly where your previous reply stopped, without repeating anything."
+
+// params returns the generation settings for the current provider.
+func (m *MainView) params() api.Params {
+	return m.paramsFor(m.currentProvider)
+}
+
+// paramsFor returns the generation settings for target: the configured
+// ones, the provider's overrides and then the session's.
+func (m *MainView) paramsFor(target string) api.Params {
+	id, _ := api.SplitTarget(target)
+	return m.globalParams.Merge(m.providerParams[id]).Merge(m.session.Params)
+}
+
+// DescribeParams lists the settings in effect and where each comes from.
+func (m *MainView) DescribeParams() string {
+	id, _ := api.SplitTarget(m.currentProvider)
+	effective := m.params()
+
+	var b strings.Builder
+	b.WriteString("⚙️ Generation parameters for " + strings.ToUpper(id) + ":\n")
+	for _, name := range api.ParamNames {
+		value, source := effective.Get(name), "default"
+		switch {
+		case m.session.Params.Get(name) != "":
+			source = "session"
+		case m.providerParams[id].Get(name) != "":
+			source = "provider config"
+		case m.globalParams.Get(name) != "":
+			source = "config"
+		}
+		if value == "" {
+			value = "-"
+		}
+		if !api.SupportsParam(id, name) {
+			source = "unsupported by " + strings.ToUpper(id)
+		}
+		fmt.Fprintf(&b, "  %-17s %-10s (%s)\n", name, value, source)
+	}
+	b.WriteString("Change with /params name=value, clear with name=, or /params reset.")
+	return b.String()
+}
+
+// SetParam changes a setting for this session. Settings the current
+// provider does not send can only be cleared.
+func (m *MainView) SetParam(name, value string) error {
+	if id := m.providerID(); strings.TrimSpace(value) != "" && !api.SupportsParam(id, name) {
+		return fmt.Errorf("%s is not supported by %s", name, strings.ToUpper(id))
+	}
+	return m.session.Params.Set(name, value)
+}
+
+func (m *MainView) ResetParams() {
+	m.session.Params = api.Params{}
+}
+
+// continueReply asks the model to finish a reply that hit the token limit.
+func (m *MainView) continueReply() tea.Cmd {
+	if m.streaming || !m.session.LastReplyTruncated() {
+		return nil
+	}
+	m.session.AddUserMessage(continuePrompt)
+	return m.startStream()
+}
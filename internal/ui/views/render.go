@@ -40,20 +40,18 @@ func (m *MainView) View() string {
 	}
 
 	// Chat mode vs Landing mode layout - based on state and user message count
-	hasUserMessages := false
-	for _, msg := range m.session.GetMessages() {
-		if strings.HasPrefix(msg, "You:") {
-			hasUserMessages = true
-			break
-		}
-	}
+	hasUserMessages := m.session.CountUserMessages() > 0
 
 	// Calculate layout dimensions - sidebar takes 30% of width when visible
 	sidebarWidth := 0
 	mainContentWidth := containerWidth
 
-	// Only show sidebar if not in active chat mode
-	showSidebarInCurrentState := m.showSidebar && !(m.state == types.StateChat || (hasUserMessages && m.state != types.StateHelp && m.state != types.StateFileBrowser && m.state != types.StateExitConfirm))
+	// Only show sidebar if not in active chat mode, unless it holds the
+	// agent panel
+	showSidebarInCurrentState := m.showSidebar && !(m.state == types.StateChat || (hasUserMessages && !m.isOverlayState()))
+	if m.agentMode && !m.isOverlayState() {
+		showSidebarInCurrentState = true
+	}
 
 	if showSidebarInCurrentState {
 		sidebarWidth = int(float64(containerWidth) * 0.3)
@@ -70,6 +68,14 @@ func (m *MainView) View() string {
 		mainView = m.renderChatView(mainContentWidth)
 	case types.StateExitConfirm:
 		mainView = m.renderExitConfirmView(mainContentWidth)
+	case types.StateToolConfirm:
+		mainView = m.renderToolConfirmView(mainContentWidth)
+	case types.StateModelPicker:
+		mainView = m.modelPicker.View(mainContentWidth-4, height)
+	case types.StateSessions:
+		mainView = m.sessionBrowser.View(mainContentWidth-4, height)
+	case types.StateCompare:
+		mainView = m.renderCompareView(mainContentWidth)
 	default:
 		if hasUserMessages {
 			mainView = m.renderChatView(mainContentWidth)
@@ -78,27 +84,36 @@ func (m *MainView) View() string {
 		}
 	}
 
+	// Calculate available height for content vs fixed bottom elements
+	statusBarHeight := 1
+	inputAreaHeight := 3
+	if len(m.attachments) > 0 {
+		inputAreaHeight++
+	}
+	availableHeight := height - statusBarHeight - inputAreaHeight - 2
+	contentHeight := availableHeight
+	if contentHeight < 5 {
+		contentHeight = 5
+	}
+	chatLayout := m.state == types.StateChat || (hasUserMessages && !m.isOverlayState())
+
 	// Add sidebar to the layout if visible
 	if showSidebarInCurrentState {
-		sidebarView := m.sidebar.View(sidebarWidth, height)
+		sidebarHeight := height
+		if chatLayout {
+			sidebarHeight = contentHeight
+		}
+		m.sidebar.SetAgentStatus(m.agentStatus())
+		m.sidebar.SetTitle(m.session.Title)
+		sidebarView := m.sidebar.View(sidebarWidth, sidebarHeight)
 		mainView = lipgloss.JoinHorizontal(lipgloss.Top, mainView, "  ", sidebarView)
 	}
 
 	// Add enhanced status bar at the bottom
 	statusBar := m.renderEnhancedStatusBar(width)
 
-	// Calculate available height for content vs fixed bottom elements
-	statusBarHeight := 1
-	inputAreaHeight := 3
-	availableHeight := height - statusBarHeight - inputAreaHeight - 2
-
 	// If we have a chat view, make sure input area sticks to bottom
-	if m.state == types.StateChat || (hasUserMessages && m.state != types.StateHelp && m.state != types.StateFileBrowser && m.state != types.StateExitConfirm) {
-		contentHeight := availableHeight
-		if contentHeight < 5 {
-			contentHeight = 5
-		}
-
+	if chatLayout {
 		// Render input area separately for chat mode
 		inputArea := m.renderInputArea(width - 4)
 
@@ -179,24 +194,6 @@ func (m *MainView) renderChatView(containerWidth int) string {
 		sections = append(sections, m.renderMessages(containerWidth-4))
 	}
 
-	// Streaming response
-	if m.streaming && m.currentResponse.Len() > 0 {
-		theme := themes.GetCurrentTheme()
-		streamingText := m.getAnimatedIcon() + " " + m.currentResponse.String() + "▎"
-
-		streamingBoxStyle := lipgloss.NewStyle().
-			Foreground(lipgloss.Color(theme.Text)).
-			Background(lipgloss.Color(theme.InputBackground)).
-			Padding(1, 2).
-			BorderLeft(true).
-			BorderStyle(lipgloss.ThickBorder()).
-			Width(containerWidth - 6).
-			MarginLeft(1)
-
-		streaming := streamingBoxStyle.Render(streamingText)
-		sections = append(sections, streaming)
-	}
-
 	// Combine all sections
 	content := strings.Join(sections, "\n\n")
 
@@ -218,12 +215,40 @@ func (m *MainView) renderMessages(width int) string {
 
 	for i := start; i < len(messages); i++ {
 		msg := messages[i]
+		theme := themes.GetCurrentTheme()
 
-		if strings.HasPrefix(msg, "You:") {
-			// User messages
-			theme := themes.GetCurrentTheme()
-			userText := strings.TrimPrefix(msg, "You: ")
+		if msg.Role == types.RoleAssistant && msg.Reasoning != "" {
+			b.WriteString(m.renderThinking(msg, width) + "\n")
+		}
 
+		switch {
+		case msg.Status == types.StatusError && msg.Error != nil:
+			b.WriteString(m.renderProviderError(msg, width) + "\n\n")
+
+		case msg.Status == types.StatusError:
+			errorStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color(theme.Error)).
+				Bold(true)
+			styled := errorStyle.Render("❌ " + msg.Content)
+			b.WriteString(styled + "\n")
+
+		case msg.Status == types.StatusSuccess:
+			successStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color(theme.Success)).
+				Bold(true)
+			styled := successStyle.Render(msg.Content)
+			b.WriteString(styled + "\n")
+
+		case msg.Status == types.StatusNotice:
+			dimStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color(theme.DimText))
+			styled := dimStyle.Render(msg.Content)
+			b.WriteString(styled + "\n")
+
+		case msg.Role == types.RoleTool:
+			b.WriteString(m.renderToolCall(msg, width) + "\n\n")
+
+		case msg.Role == types.RoleUser:
 			userBoxStyle := lipgloss.NewStyle().
 				Foreground(lipgloss.Color(theme.Text)).
 				Background(lipgloss.Color(theme.InputBackground)).
@@ -234,7 +259,11 @@ func (m *MainView) renderMessages(width int) string {
 				Width(width - 6).
 				MarginLeft(1)
 
-			styledUser := userBoxStyle.Render(userText)
+			userContent := msg.Content
+			if len(msg.Attachments) > 0 {
+				userContent += "\n\n" + m.renderAttachmentChips(msg.Attachments)
+			}
+			styledUser := userBoxStyle.Render(userContent)
 
 			rightAlignedUser := lipgloss.NewStyle().
 				Width(width).
@@ -242,12 +271,29 @@ func (m *MainView) renderMessages(width int) string {
 
 			b.WriteString(rightAlignedUser + "\n\n")
 
-		} else if strings.HasPrefix(msg, "AI:") {
-			// AI responses
-			theme := themes.GetCurrentTheme()
-			responseText := strings.TrimPrefix(msg, "AI: ")
+		case msg.Status == types.StatusStreaming:
+			if msg.Content == "" {
+				continue
+			}
+			streamingText := m.getAnimatedIcon() + " " + msg.Content + "▎"
 
-			responseWithIcon := m.getAnimatedIcon() + " " + responseText
+			streamingBoxStyle := lipgloss.NewStyle().
+				Foreground(lipgloss.Color(theme.Text)).
+				Background(lipgloss.Color(theme.InputBackground)).
+				Padding(1, 2).
+				BorderLeft(true).
+				BorderStyle(lipgloss.ThickBorder()).
+				Width(width - 6).
+				MarginLeft(1)
+
+			b.WriteString(streamingBoxStyle.Render(streamingText) + "\n\n")
+
+		case msg.Role == types.RoleAssistant && msg.Content == "":
+			// A reply that only calls tools is shown by its tool blocks
+			continue
+
+		case msg.Role == types.RoleAssistant:
+			responseWithIcon := m.getAnimatedIcon() + " " + msg.Content
 
 			boxStyle := lipgloss.NewStyle().
 				Foreground(lipgloss.Color(theme.Text)).
@@ -260,27 +306,56 @@ func (m *MainView) renderMessages(width int) string {
 				MarginLeft(1)
 
 			styledResponse := boxStyle.Render(responseWithIcon)
-			b.WriteString(styledResponse + "\n\n")
-
-		} else if strings.HasPrefix(msg, "❌") {
-			theme := themes.GetCurrentTheme()
-			errorStyle := lipgloss.NewStyle().
-				Foreground(lipgloss.Color(theme.Error)).
-				Bold(true)
-			styled := errorStyle.Render(msg)
-			b.WriteString(styled + "\n")
-		} else if strings.HasPrefix(msg, "🎉") || strings.HasPrefix(msg, "🔗") || strings.HasPrefix(msg, "📁") || strings.HasPrefix(msg, "📋") || strings.HasPrefix(msg, "🎨") {
-			theme := themes.GetCurrentTheme()
-			successStyle := lipgloss.NewStyle().
-				Foreground(lipgloss.Color(theme.Success)).
-				Bold(true)
-			styled := successStyle.Render(msg)
-			b.WriteString(styled + "\n")
-		} else {
-			theme := themes.GetCurrentTheme()
+			b.WriteString(styledResponse + "\n")
+
+			if msg.Status == types.StatusInterrupted {
+				interruptedStyle := lipgloss.NewStyle().
+					Foreground(lipgloss.Color(theme.Warning)).
+					Italic(true).
+					MarginLeft(2)
+				b.WriteString(interruptedStyle.Render("⏹ Interrupted") + "\n")
+			}
+			if msg.Truncated {
+				truncatedStyle := lipgloss.NewStyle().
+					Foreground(lipgloss.Color(theme.Warning)).
+					MarginLeft(2)
+				truncatedText := "✂ Cut off at the token limit"
+				if i == len(messages)-1 && !m.streaming {
+					truncatedText += " · Ctrl+N to continue"
+				}
+				b.WriteString(truncatedStyle.Render(truncatedText) + "\n")
+			}
+			if msg.Schema != "" {
+				schemaStyle := lipgloss.NewStyle().
+					Foreground(lipgloss.Color(theme.Success)).
+					MarginLeft(2)
+				b.WriteString(schemaStyle.Render("🧾 Valid JSON for the "+msg.Schema+" schema") + "\n")
+			}
+			if msg.FallbackFrom != "" {
+				fallbackStyle := lipgloss.NewStyle().
+					Foreground(lipgloss.Color(theme.Warning)).
+					MarginLeft(2)
+				b.WriteString(fallbackStyle.Render(fmt.Sprintf("↪ Answered by %s · %s (%s failed)", strings.ToUpper(msg.Provider), msg.Model, strings.ToUpper(msg.FallbackFrom))) + "\n")
+			}
+			if msg.Usage != nil {
+				usageStyle := lipgloss.NewStyle().
+					Foreground(lipgloss.Color(theme.DimText)).
+					MarginLeft(2)
+				usageText := fmt.Sprintf("↑%s ↓%s", formatTokens(msg.Usage.PromptTokens), formatTokens(msg.Usage.CompletionTokens))
+				if msg.Usage.CachedTokens > 0 {
+					usageText += fmt.Sprintf(" (%s cached)", formatTokens(msg.Usage.CachedTokens))
+				}
+				if msg.Usage.Priced {
+					usageText += " · " + formatCost(msg.Usage.Cost)
+				}
+				b.WriteString(usageStyle.Render(usageText) + "\n")
+			}
+			b.WriteString("\n")
+
+		default:
 			dimStyle := lipgloss.NewStyle().
 				Foreground(lipgloss.Color(theme.DimText))
-			styled := dimStyle.Render(msg)
+			styled := dimStyle.Render(msg.Content)
 			b.WriteString(styled + "\n")
 		}
 	}
@@ -1,13 +1,16 @@
 package views
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"Chat2/internal/themes"
+	"Chat2/internal/types"
 	"Chat2/internal/ui"
 
 	"github.com/charmbracelet/lipgloss"
@@ -18,14 +21,20 @@ func (m *MainView) renderInputArea(width int) string {
 
 	// Directory path and provider info
 	projectPath := getCurrentProjectPath()
-	modelName := "Anthropic Claude"
+	modelName := "No provider"
 	if len(m.availableProviders) > 0 {
-		modelName = strings.ToUpper(m.currentProvider)
+		modelName = strings.ToUpper(m.currentProvider) + " · " + m.currentModel()
 	}
 
 	// Create boxed elements
 	pathText := "📁 " + projectPath
 	modelText := "🧠 " + modelName
+	if m.jsonSchema != nil {
+		modelText += " · 🧾 " + m.jsonSchema.Name
+	}
+	if m.compareTargets != nil {
+		modelText = fmt.Sprintf("⚖️ Comparing %d models", len(m.compareTargets))
+	}
 
 	pathElement := lipgloss.NewStyle().
 		Foreground(lipgloss.Color(theme.DimText)).
@@ -52,8 +61,12 @@ func (m *MainView) renderInputArea(width int) string {
 	// Get input field view
 	inputView := m.input.View()
 
-	// Combine header and input
-	inputContent := headerLine + "\n\n" + inputView
+	// Combine header, attachment chips and input
+	inputContent := headerLine + "\n\n"
+	if len(m.attachments) > 0 {
+		inputContent += m.renderAttachmentChips(m.attachments) + "\n"
+	}
+	inputContent += inputView
 
 	// Create input container
 	inputBoxStyle := lipgloss.NewStyle().
@@ -68,6 +81,22 @@ func (m *MainView) renderInputArea(width int) string {
 	return inputBoxStyle.Render(inputContent)
 }
 
+// renderAttachmentChips renders attachments as a row of chips.
+func (m *MainView) renderAttachmentChips(attachments []types.Attachment) string {
+	theme := themes.GetCurrentTheme()
+	chipStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Background)).
+		Background(lipgloss.Color(theme.Highlight)).
+		Padding(0, 1).
+		MarginRight(1)
+
+	chips := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		chips = append(chips, chipStyle.Render(attachmentLabel(attachment)))
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, chips...)
+}
+
 func (m *MainView) renderFeatureCard(width int) string {
 	description := m.getAnimatedIcon() + " Effortless, Intuitive, Lightning-fast AI CLI."
 
@@ -159,23 +188,22 @@ func (m *MainView) renderEnhancedStatusBar(width int) string {
 	connectionStatus := "🟢 Online"
 	if len(m.availableProviders) == 0 {
 		connectionStatus = "🔴 No API Keys"
+	} else if m.streamStatus != "" {
+		connectionStatus = m.streamStatus
 	} else if m.streaming {
-		connectionStatus = "🔄 Streaming"
+		connectionStatus = "🔄 Streaming (Esc to stop)"
 	}
 
-	messages := m.session.GetMessages()
-	messageCount := len(messages)
-	userMessages := 0
-	for _, msg := range messages {
-		if strings.HasPrefix(msg, "You:") {
-			userMessages++
-		}
-	}
+	messageCount := len(m.session.GetMessages())
+	userMessages := m.session.CountUserMessages()
 
 	sessionInfo := fmt.Sprintf("💬 %d msgs", messageCount)
 	if userMessages > 0 {
 		sessionInfo = fmt.Sprintf("💬 %d/%d msgs", userMessages, messageCount)
 	}
+	if usage := m.session.Usage; usage.TotalTokens() > 0 {
+		sessionInfo += "  🪙 " + formatUsage(usage)
+	}
 
 	currentTime := fmt.Sprintf("🕐 %s", getCurrentTime())
 	copilotIndicator := fmt.Sprintf("PUKU-%s", strings.ToUpper(m.currentProvider))
@@ -203,6 +231,187 @@ func (m *MainView) renderEnhancedStatusBar(width int) string {
 	return statusStyle.Render(statusContent)
 }
 
+// errorTitles names each kind of provider error for the error box.
+var errorTitles = map[string]string{
+	"auth":            "🔑 Authentication failed",
+	"quota":           "💳 Quota exhausted",
+	"rate_limit":      "⏳ Rate limited",
+	"model_not_found": "🧩 Unknown model",
+	"bad_request":     "🚫 Request rejected",
+	"server":          "🔥 Provider error",
+	"network":         "🌐 Connection failed",
+	"stream":          "📡 Broken stream",
+}
+
+func (m *MainView) renderProviderError(msg types.Message, width int) string {
+	theme := themes.GetCurrentTheme()
+	detail := msg.Error
+
+	title, ok := errorTitles[detail.Kind]
+	if !ok {
+		title = "❌ Error"
+	}
+	if detail.Provider != "" {
+		title += " · " + detail.Provider
+	}
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Error)).
+		Bold(true)
+	textStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Text))
+	dimStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.DimText))
+
+	lines := []string{titleStyle.Render(title), textStyle.Render(msg.Content)}
+
+	var facts []string
+	if detail.StatusCode != 0 {
+		facts = append(facts, fmt.Sprintf("status %d", detail.StatusCode))
+	}
+	if detail.Type != "" {
+		facts = append(facts, "type "+detail.Type)
+	}
+	if detail.RetryAfter > 0 {
+		facts = append(facts, "retry after "+detail.RetryAfter.Round(time.Second).String())
+	}
+	if detail.RequestID != "" {
+		facts = append(facts, "request "+detail.RequestID)
+	}
+	if len(facts) > 0 {
+		lines = append(lines, dimStyle.Render(strings.Join(facts, " • ")))
+	}
+	if detail.Hint != "" {
+		lines = append(lines, dimStyle.Italic(true).Render("💡 "+detail.Hint))
+	}
+
+	boxStyle := lipgloss.NewStyle().
+		Padding(0, 2).
+		BorderLeft(true).
+		BorderStyle(lipgloss.ThickBorder()).
+		BorderForeground(lipgloss.Color(theme.Error)).
+		Width(width - 6).
+		MarginLeft(1)
+
+	return boxStyle.Render(strings.Join(lines, "\n"))
+}
+
+// maxToolOutputLines caps how much of a tool's output an expanded block
+// shows.
+const maxToolOutputLines = 30
+
+// thinkingTailLines is how much of the reasoning a collapsed thinking
+// section shows while the model is still thinking.
+const thinkingTailLines = 3
+
+// renderThinking renders a reply's reasoning as a dimmed section. Collapsed
+// it is one summary line, plus the latest lines while the model thinks;
+// Ctrl+T expands it to the full text.
+func (m *MainView) renderThinking(msg types.Message, width int) string {
+	theme := themes.GetCurrentTheme()
+	dimStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.DimText)).
+		Italic(true)
+
+	lines := strings.Split(strings.TrimSpace(msg.Reasoning), "\n")
+	thinking := msg.Status == types.StatusStreaming && msg.Content == ""
+
+	if !m.expandThinking {
+		summary := fmt.Sprintf("▸ 💭 Thought for %d lines · Ctrl+T to show", len(lines))
+		if thinking {
+			summary = "▸ 💭 Thinking…"
+		}
+		section := summary
+		if thinking {
+			section += "\n" + strings.Join(lines[max(len(lines)-thinkingTailLines, 0):], "\n")
+		}
+		return dimStyle.MarginLeft(2).Width(width - 4).Render(section)
+	}
+
+	header := "▾ 💭 Thinking · Ctrl+T to hide"
+	boxStyle := dimStyle.
+		Padding(0, 2).
+		BorderLeft(true).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color(theme.Border)).
+		Width(width - 6).
+		MarginLeft(1)
+	return boxStyle.Render(header + "\n" + strings.Join(lines, "\n"))
+}
+
+// runningToolLines is how much output a collapsed block shows while its
+// tool runs.
+const runningToolLines = 5
+
+// renderToolCall shows a tool call and its result as a block that is a
+// one-line summary while collapsed; Ctrl+O expands it to the arguments and
+// output.
+func (m *MainView) renderToolCall(msg types.Message, width int) string {
+	theme := themes.GetCurrentTheme()
+	call := msg.Tool
+
+	status, color := "✓", theme.Success
+	switch {
+	case msg.Status == types.StatusRunning:
+		status, color = "⏳", theme.Warning
+	case msg.Status == types.StatusInterrupted:
+		status, color = "⏹", theme.Warning
+	case call.Failed:
+		status, color = "✗", theme.Error
+	}
+
+	lines := strings.Split(strings.TrimRight(msg.Content, "\n"), "\n")
+	if msg.Content == "" {
+		lines = nil
+	}
+
+	dimStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.DimText))
+	nameStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Primary)).
+		Bold(true)
+	statusStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(color))
+
+	args := strings.Join(strings.Fields(call.Arguments), " ")
+	if !m.expandTools {
+		summary := fmt.Sprintf("%d lines", len(lines))
+		if msg.Status == types.StatusRunning {
+			summary = "running…"
+		}
+		maxArgs := width - lipgloss.Width(call.Name) - len(summary) - 16
+		if runes := []rune(args); maxArgs > 0 && len(runes) > maxArgs {
+			args = string(runes[:maxArgs]) + "…"
+		}
+		header := "▸ 🔧 " + nameStyle.Render(call.Name) + " " + dimStyle.Render(args) + "  " + statusStyle.Render(status+" "+summary)
+		if msg.Status == types.StatusRunning && len(lines) > 0 {
+			// Show the latest output of a tool that is still running
+			tail := lines[max(len(lines)-runningToolLines, 0):]
+			header += "\n" + dimStyle.Render(strings.Join(tail, "\n"))
+		}
+		return lipgloss.NewStyle().MarginLeft(2).Render(header)
+	}
+
+	header := "▾ 🔧 " + nameStyle.Render(call.Name) + "  " + statusStyle.Render(status)
+	body := []string{header, dimStyle.Render("args: " + args)}
+	if len(lines) > maxToolOutputLines {
+		hidden := len(lines) - maxToolOutputLines
+		lines = append(lines[:maxToolOutputLines:maxToolOutputLines], fmt.Sprintf("… %d more lines", hidden))
+	}
+	body = append(body, lines...)
+
+	boxStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Text)).
+		Padding(0, 2).
+		BorderLeft(true).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(lipgloss.Color(color)).
+		Width(width - 6).
+		MarginLeft(1)
+
+	return boxStyle.Render(strings.Join(body, "\n"))
+}
+
 func (m *MainView) renderHelpView(containerWidth int) string {
 	styles := ui.GetStyles()
 	theme := themes.GetCurrentTheme()
@@ -225,8 +434,15 @@ func (m *MainView) renderHelpView(containerWidth int) string {
 		"?               Show/hide this help",
 		"Tab             Switch between providers",
 		"Ctrl+P          Toggle provider list",
+		"/model          Pick a model (Ctrl+R refreshes the list)",
 		"Ctrl+C          Quit application",
 		"Esc             Cancel/Go back",
+		"Ctrl+X          Stop a streaming reply (Esc too)",
+		"Ctrl+O          Expand/collapse tool calls",
+		"/agent          Toggle agent mode (Enter steers it)",
+		"/attach <path>  Attach a file (Backspace removes it)",
+		"Ctrl+N          Continue a reply cut off at the token limit",
+		"Ctrl+T          Show/hide model reasoning",
 		"Enter           Send message/Execute command",
 	}
 
@@ -281,17 +497,65 @@ func (m *MainView) renderFileBrowserView(containerWidth int) string {
 		Render("📍 Current Path: " + m.fileBrowserPath)
 	sections = append(sections, pathDisplay)
 
-	// Directory contents would be implemented here
+	sections = append(sections, m.renderFileBrowserItems(containerWidth))
+
 	instructions := lipgloss.NewStyle().
 		Foreground(lipgloss.Color(theme.DimText)).
 		Italic(true).
-		Render("📋 Navigation: ↑↓ to select, Enter to open, ESC to go back")
+		Render("📋 Navigation: ↑↓ to select, Enter to open a folder or attach a file, Backspace for the parent folder, ESC to go back")
 	sections = append(sections, instructions)
 
 	content := strings.Join(sections, "\n\n")
 	return styles.Container.Width(containerWidth).Render(content)
 }
 
+// fileBrowserRows is how many entries the file browser shows at once.
+const fileBrowserRows = 15
+
+// renderFileBrowserItems lists the entries around the selected one.
+func (m *MainView) renderFileBrowserItems(width int) string {
+	theme := themes.GetCurrentTheme()
+	if m.fileBrowserErr != "" {
+		return lipgloss.NewStyle().
+			Foreground(lipgloss.Color(theme.Error)).
+			Render("❌ " + m.fileBrowserErr)
+	}
+
+	start := 0
+	if m.fileBrowserSelected >= fileBrowserRows {
+		start = m.fileBrowserSelected - fileBrowserRows + 1
+	}
+	end := min(start+fileBrowserRows, len(m.fileBrowserItems))
+
+	itemStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Text))
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Primary)).
+		Bold(true)
+
+	var lines []string
+	for i := start; i < end; i++ {
+		item := m.fileBrowserItems[i]
+		icon := "📄 "
+		if strings.HasSuffix(item, "/") {
+			icon = "📁 "
+		}
+		if i == m.fileBrowserSelected {
+			lines = append(lines, selectedStyle.Render("› "+icon+item))
+		} else {
+			lines = append(lines, itemStyle.Render("  "+icon+item))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, itemStyle.Render("  (empty folder)"))
+	}
+	if hidden := len(m.fileBrowserItems) - end; hidden > 0 {
+		lines = append(lines, lipgloss.NewStyle().
+			Foreground(lipgloss.Color(theme.DimText)).
+			Render(fmt.Sprintf("  … %d more", hidden)))
+	}
+	return lipgloss.NewStyle().Width(width).Render(strings.Join(lines, "\n"))
+}
+
 func (m *MainView) renderExitConfirmView(containerWidth int) string {
 	theme := themes.GetCurrentTheme()
 
@@ -371,6 +635,126 @@ func (m *MainView) renderExitConfirmView(containerWidth int) string {
 	return containerStyle.Render(dialog)
 }
 
+// maxApprovalLines caps the argument preview in the tool approval dialog.
+const maxApprovalLines = 16
+
+func (m *MainView) renderToolConfirmView(containerWidth int) string {
+	theme := themes.GetCurrentTheme()
+	call := m.pendingApproval
+	if call == nil {
+		return ""
+	}
+
+	dialogWidth := min(80, containerWidth-4)
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(theme.Text)).
+		Width(dialogWidth - 4).
+		Render("✋ Allow the model to run " + call.Name + "?")
+
+	preview := strings.Split(formatToolArguments(call.Arguments), "\n")
+	if len(preview) > maxApprovalLines {
+		hidden := len(preview) - maxApprovalLines
+		preview = append(preview[:maxApprovalLines:maxApprovalLines], fmt.Sprintf("… %d more lines", hidden))
+	}
+	args := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.DimText)).
+		Width(dialogWidth - 4).
+		Render(strings.Join(preview, "\n"))
+
+	options := []string{"Allow once", "Always this session", "Deny"}
+	var buttons []string
+	for i, option := range options {
+		if i == approveAlways && !m.canApproveAlways() {
+			continue
+		}
+		style := lipgloss.NewStyle().
+			Padding(0, 2).
+			Margin(0, 1)
+		if i == m.approvalSelected {
+			style = style.
+				Background(lipgloss.Color(theme.Primary)).
+				Foreground(lipgloss.Color("#ffffff")).
+				Bold(true)
+		} else {
+			style = style.
+				Border(lipgloss.NormalBorder()).
+				BorderForeground(lipgloss.Color(theme.Border)).
+				Foreground(lipgloss.Color(theme.Text))
+		}
+		buttons = append(buttons, style.Render(option))
+	}
+
+	hint := "Always allows every " + call.Name + " call until the session ends\n" +
+		"← → to choose • Enter to confirm • y once • a always • n/ESC deny"
+	if !m.canApproveAlways() {
+		hint = "Commands are not sandboxed and are approved one at a time\n" +
+			"← → to choose • Enter to confirm • y allow • n/ESC deny"
+	}
+	instructions := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.DimText)).
+		Align(lipgloss.Center).
+		Width(dialogWidth - 4).
+		Render(hint)
+
+	dialogContent := lipgloss.JoinVertical(
+		lipgloss.Center,
+		title,
+		"",
+		args,
+		"",
+		lipgloss.JoinHorizontal(lipgloss.Center, buttons...),
+		"",
+		instructions,
+	)
+
+	dialogStyle := lipgloss.NewStyle().
+		Border(lipgloss.DoubleBorder()).
+		BorderForeground(lipgloss.Color(theme.Warning)).
+		Background(lipgloss.Color(theme.Background)).
+		Padding(1, 2).
+		Width(dialogWidth)
+
+	containerStyle := lipgloss.NewStyle().
+		Width(containerWidth).
+		Height(m.height-4).
+		Align(lipgloss.Center, lipgloss.Center)
+
+	return containerStyle.Render(dialogStyle.Render(dialogContent))
+}
+
+// formatToolArguments lists a tool call's arguments one per line, showing
+// multi-line strings such as file contents as they would appear on disk.
+func formatToolArguments(arguments string) string {
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+		return arguments
+	}
+
+	keys := make([]string, 0, len(args))
+	for key := range args {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		switch value := args[key].(type) {
+		case string:
+			if strings.Contains(value, "\n") {
+				lines = append(lines, key+":", value)
+			} else {
+				lines = append(lines, key+": "+value)
+			}
+		default:
+			encoded, _ := json.Marshal(value)
+			lines = append(lines, key+": "+string(encoded))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (m *MainView) getAnimatedIcon() string {
 	theme := themes.GetCurrentTheme()
 	icons := []string{"❋", "✻", "+", ".", "-"}
@@ -392,6 +776,29 @@ func getCurrentProjectPath() string {
 	return "~/" + projectName
 }
 
+// formatUsage summarises token counts and cost, e.g. "12.3k tok $0.0042".
+func formatUsage(usage types.TokenUsage) string {
+	text := formatTokens(usage.TotalTokens()) + " tok"
+	if usage.Priced || usage.Cost > 0 {
+		text += " " + formatCost(usage.Cost)
+	}
+	return text
+}
+
+func formatTokens(tokens int) string {
+	if tokens >= 1000 {
+		return fmt.Sprintf("%.1fk", float64(tokens)/1000)
+	}
+	return fmt.Sprintf("%d", tokens)
+}
+
+func formatCost(cost float64) string {
+	if cost >= 0.01 {
+		return fmt.Sprintf("$%.2f", cost)
+	}
+	return fmt.Sprintf("$%.4f", cost)
+}
+
 func getCurrentTime() string {
 	now := time.Now()
 	return now.Format("15:04")
@@ -417,4 +824,4 @@ func stripANSI(s string) string {
 	}
 
 	return strings.Repeat("x", visibleCount)
-}
\ No newline at end of file
+}
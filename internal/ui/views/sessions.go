@@ -0,0 +1,303 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	"Chat2/internal/api"
+	"Chat2/internal/chat"
+	"Chat2/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// previewTurns is how many turns of the highlighted session the browser
+// previews.
+const previewTurns = 4
+
+// resumeTarget is the provider and model a resumed session last used.
+type resumeTarget struct {
+	provider string
+	model    string
+}
+
+// saveSession writes the conversation to the session store. A failure is
+// reported once rather than after every turn.
+func (m *MainView) saveSession() {
+	err := m.store.Save(m.session)
+	if err == nil {
+		m.saveErr = ""
+		return
+	}
+	if err.Error() != m.saveErr {
+		m.saveErr = err.Error()
+		m.session.AddErrorMessage("Could not save the session: " + err.Error())
+	}
+}
+
+// OpenSessionBrowser lists the saved sessions to resume or manage.
+func (m *MainView) OpenSessionBrowser() tea.Cmd {
+	if m.state != types.StateSessions {
+		m.previousState = m.state
+	}
+	m.state = types.StateSessions
+
+	sessions, err := m.store.List()
+	cmd := m.sessionBrowser.Open(sessions, m.session.ID, err)
+	m.refreshSessionPreview()
+	return cmd
+}
+
+// ContinueLatest resumes the most recently updated session, as the
+// --continue flag asks.
+func (m *MainView) ContinueLatest() {
+	s, err := m.store.Latest()
+	switch {
+	case err != nil:
+		m.session.AddErrorMessage("Cannot continue the last session: " + err.Error())
+	case s == nil:
+		m.session.AddNotice("No saved session to continue; starting a new one.")
+	default:
+		m.resumeSession(s)
+	}
+}
+
+func (m *MainView) handleSessionBrowserKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	b := m.sessionBrowser
+
+	if b.ConfirmingDelete() {
+		if msg.Type == tea.KeyRunes && strings.EqualFold(string(msg.Runes), "y") {
+			m.deleteSelectedSession()
+		}
+		return m, b.Cancel()
+	}
+
+	if title, renaming := b.Renaming(); renaming {
+		switch msg.Type {
+		case tea.KeyEnter:
+			m.renameSelectedSession(title)
+			return m, b.Cancel()
+		case tea.KeyEsc:
+			return m, b.Cancel()
+		}
+		var cmd tea.Cmd
+		m.sessionBrowser, cmd = b.Update(msg)
+		return m, cmd
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.state = m.previousState
+		return m, nil
+	case tea.KeyEnter:
+		m.openSelectedSession()
+		return m, nil
+	case tea.KeyCtrlR:
+		return m, b.StartRename()
+	case tea.KeyCtrlD:
+		m.duplicateSelectedSession()
+		return m, nil
+	case tea.KeyDelete:
+		b.StartDelete()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.sessionBrowser, cmd = b.Update(msg)
+	m.refreshSessionPreview()
+	return m, cmd
+}
+
+// refreshSessionPreview loads the first turns of the highlighted session
+// when the highlight moved to another one.
+func (m *MainView) refreshSessionPreview() {
+	info, ok := m.sessionBrowser.Selected()
+	if !ok || info.ID == m.sessionBrowser.PreviewID() {
+		return
+	}
+
+	s, err := m.store.Load(info.ID)
+	if err != nil {
+		m.sessionBrowser.SetPreview(info.ID, []string{"❌ " + err.Error()})
+		return
+	}
+
+	var lines []string
+	for _, msg := range s.Messages {
+		if len(lines) == previewTurns {
+			break
+		}
+		content := strings.Join(strings.Fields(msg.Content), " ")
+		switch msg.Role {
+		case types.RoleUser:
+			lines = append(lines, "› "+content)
+		case types.RoleAssistant:
+			if content != "" {
+				lines = append(lines, "  "+content)
+			}
+		}
+	}
+	m.sessionBrowser.SetPreview(info.ID, lines)
+}
+
+// reloadSessionBrowser lists the sessions again after one was changed.
+func (m *MainView) reloadSessionBrowser() {
+	sessions, err := m.store.List()
+	m.sessionBrowser.SetSessions(sessions, err)
+	m.refreshSessionPreview()
+}
+
+func (m *MainView) openSelectedSession() {
+	info, ok := m.sessionBrowser.Selected()
+	if !ok {
+		return
+	}
+	m.state = m.previousState
+	if info.ID == m.session.ID {
+		return
+	}
+
+	s, err := m.store.Load(info.ID)
+	if err != nil {
+		m.session.AddErrorMessage("Cannot open the session: " + err.Error())
+		return
+	}
+	m.resumeSession(s)
+}
+
+// resumeSession makes s the current session and switches back to the
+// provider and model it was using, if they are still available.
+func (m *MainView) resumeSession(s *chat.Session) {
+	if m.streaming {
+		m.stopStreaming()
+	}
+	m.session = s
+	m.attachments = nil
+	m.agentPlan = nil
+	m.steering = nil
+	m.saveErr = ""
+	m.pendingTarget = nil
+
+	var model string
+	for i := len(s.Messages) - 1; i >= 0 && model == ""; i-- {
+		if msg := s.Messages[i]; msg.Role == types.RoleAssistant && msg.Model != "" {
+			model = msg.Model
+		}
+	}
+	if model != "" && !m.switchToTarget(s.CurrentProvider, model) {
+		target := resumeTarget{provider: s.CurrentProvider, model: model}
+		if m.localModelsLoaded {
+			m.reportUnavailable(target)
+		} else {
+			// A local model may only be missing because discovery is still
+			// running, as it is when --continue resumes at startup
+			m.pendingTarget = &target
+		}
+	}
+	m.session.CurrentProvider = m.currentProvider
+
+	m.enterChat()
+	m.session.AddMessage(types.NewSuccess("📂 Resumed " + sessionName(s)))
+}
+
+// restorePendingTarget switches to the model of the resumed session once
+// local models are known, unless the user picked another one meanwhile.
+func (m *MainView) restorePendingTarget() {
+	target := m.pendingTarget
+	m.pendingTarget = nil
+	if target == nil || m.streaming {
+		return
+	}
+	if !m.switchToTarget(target.provider, target.model) {
+		m.reportUnavailable(*target)
+		return
+	}
+	m.session.CurrentProvider = m.currentProvider
+}
+
+func (m *MainView) reportUnavailable(target resumeTarget) {
+	m.session.AddNotice(fmt.Sprintf("%s · %s is not available; continuing with %s · %s.", strings.ToUpper(target.provider), target.model, strings.ToUpper(m.providerID()), m.currentModel()))
+}
+
+// switchToTarget makes provider the current provider with model selected
+// for it, for this run only. It reports false if the provider is not set
+// up or, for local providers, does not have the model.
+func (m *MainView) switchToTarget(provider, model string) bool {
+	for _, candidate := range m.availableProviders {
+		id, pinned := api.SplitTarget(candidate)
+		if id != provider || (pinned != "" && pinned != model) {
+			continue
+		}
+		if pinned == "" {
+			m.selectedModels[id] = model
+		}
+		m.currentProvider = candidate
+		m.sidebar.SetCurrentProvider(candidate)
+		return true
+	}
+	return false
+}
+
+func (m *MainView) providerID() string {
+	id, _ := api.SplitTarget(m.currentProvider)
+	return id
+}
+
+func (m *MainView) renameSelectedSession(title string) {
+	info, ok := m.sessionBrowser.Selected()
+	if !ok || title == "" {
+		return
+	}
+	if info.ID == m.session.ID {
+		m.session.Title = title
+	}
+	if err := m.store.Rename(info.ID, title); err != nil {
+		m.session.AddErrorMessage("Cannot rename the session: " + err.Error())
+	}
+	m.reloadSessionBrowser()
+}
+
+func (m *MainView) duplicateSelectedSession() {
+	info, ok := m.sessionBrowser.Selected()
+	if !ok {
+		return
+	}
+	if _, err := m.store.Duplicate(info.ID); err != nil {
+		m.session.AddErrorMessage("Cannot duplicate the session: " + err.Error())
+	}
+	m.reloadSessionBrowser()
+}
+
+// deleteSelectedSession removes the highlighted session. Deleting the open
+// session starts a new one, so that the next turn does not save it again.
+func (m *MainView) deleteSelectedSession() {
+	info, ok := m.sessionBrowser.Selected()
+	if !ok {
+		return
+	}
+	if err := m.store.Delete(info.ID); err != nil {
+		m.session.AddErrorMessage("Cannot delete the session: " + err.Error())
+	}
+	if info.ID == m.session.ID {
+		m.session.Clear()
+		m.agentPlan = nil
+	}
+	m.reloadSessionBrowser()
+}
+
+// sessionName quotes the title of s, or its first prompt until it has one.
+func sessionName(s *chat.Session) string {
+	if s.Title != "" {
+		return "“" + s.Title + "”"
+	}
+	for _, msg := range s.Messages {
+		if msg.Role == types.RoleUser {
+			content, _, _ := strings.Cut(strings.TrimSpace(msg.Content), "\n")
+			if runes := []rune(content); len(runes) > 40 {
+				content = string(runes[:39]) + "…"
+			}
+			return "“" + content + "”"
+		}
+	}
+	return "session"
+}
@@ -0,0 +1,213 @@
+package views
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"Chat2/internal/api"
+	"Chat2/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// streamEventMsg delivers the next event of a streamed reply. id tells
+// events of a stopped stream apart from the current one.
+type streamEventMsg struct {
+	id     int
+	event  api.StreamEvent
+	events <-chan api.StreamEvent
+	closed bool
+}
+
+// waitForStream returns a command that waits for the next event on events.
+func waitForStream(id int, events <-chan api.StreamEvent) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-events
+		return streamEventMsg{id: id, event: event, events: events, closed: !ok}
+	}
+}
+
+// startStream sends the conversation to the current provider with a context
+// that stopStreaming can cancel. The context stays in use while the reply
+// runs tools and is sent back for the next round.
+func (m *MainView) startStream() tea.Cmd {
+	m.streamCtx, m.cancelStream = context.WithCancel(context.Background())
+	m.streaming = true
+	m.toolRounds = 0
+	m.agentTokens = 0
+	m.schemaRetries = 0
+	m.saveSession()
+	return m.requestReply()
+}
+
+// requestReply streams the next assistant reply to the conversation so far.
+func (m *MainView) requestReply() tea.Cmd {
+	m.streamID++
+	m.streamTarget = m.currentTarget()
+	m.flushSteering()
+
+	req := api.ChatRequest{
+		Messages:       m.session.History(m.contextBudget),
+		Params:         m.params(),
+		Tools:          m.tools.Definitions(),
+		ResponseFormat: m.responseFormat(),
+	}
+	if m.agentMode {
+		req = m.agentRequest(req)
+	}
+	m.session.BeginResponse(m.currentProvider, m.currentModel())
+	events := api.SendToAI(m.streamCtx, m.streamTarget, req, m.apiKeys)
+	return waitForStream(m.streamID, events)
+}
+
+// handleStreamEvent applies a stream event to the session and waits for the
+// next one until the stream ends.
+func (m *MainView) handleStreamEvent(msg streamEventMsg) tea.Cmd {
+	if msg.id != m.streamID || !m.streaming {
+		return nil
+	}
+
+	if msg.closed {
+		m.session.FinishResponse()
+		m.endStream()
+		return nil
+	}
+
+	switch msg.event.Type {
+	case api.EventRetry:
+		m.streamStatus = fmt.Sprintf("🔁 Retrying (%d/%d)…", msg.event.Attempt, msg.event.MaxAttempts)
+	case api.EventFallback:
+		failed, _ := api.SplitTarget(m.streamTarget)
+		m.streamTarget = msg.event.Target
+		id, _ := api.SplitTarget(m.streamTarget)
+		m.session.FallBackResponse(id, api.DefaultModel(m.streamTarget))
+		m.streamStatus = fmt.Sprintf("↪ %s failed, trying %s…", strings.ToUpper(failed), strings.ToUpper(id))
+	case api.EventDelta:
+		m.streamStatus = ""
+		m.session.AppendToResponse(msg.event.Text)
+	case api.EventReasoning:
+		m.streamStatus = ""
+		m.session.AppendReasoning(msg.event.Text)
+		if msg.event.Signature != "" {
+			m.session.SetReasoningSignature(msg.event.Signature)
+		}
+	case api.EventTruncated:
+		m.session.MarkResponseTruncated()
+	case api.EventUsage:
+		m.session.SetResponseUsage(m.tokenUsage(*msg.event.Usage))
+		if m.agentMode {
+			m.agentTokens += msg.event.Usage.PromptTokens + msg.event.Usage.CompletionTokens
+		}
+	case api.EventToolCalls:
+		calls := make([]types.ToolCall, 0, len(msg.event.ToolCalls))
+		for _, call := range msg.event.ToolCalls {
+			calls = append(calls, types.ToolCall(call))
+		}
+		m.session.SetResponseToolCalls(calls)
+		m.pendingTools = calls
+	case api.EventDone:
+		if len(m.pendingTools) == 0 && m.jsonSchema != nil {
+			if err := m.formatStructuredReply(); err != nil {
+				return m.rejectStructuredReply(err)
+			}
+		}
+		m.session.FinishResponse()
+		// In agent mode, messages queued during the last step are answered
+		// in another round rather than waiting for the next turn.
+		if len(m.pendingTools) > 0 || (m.agentMode && len(m.steering) > 0) {
+			return m.runNextTool()
+		}
+		m.endStream()
+		return m.requestTitle()
+	case api.EventError:
+		m.session.InterruptResponse()
+		m.addStreamError(msg.event.Err)
+		m.endStream()
+		return nil
+	}
+
+	return waitForStream(msg.id, msg.events)
+}
+
+// tokenUsage prices the usage reported for the provider that is answering.
+func (m *MainView) tokenUsage(usage api.Usage) types.TokenUsage {
+	return priceUsage(m.streamTarget, usage)
+}
+
+// priceUsage prices usage reported by target.
+func priceUsage(target string, usage api.Usage) types.TokenUsage {
+	cost, priced := api.Cost(target, usage)
+	return types.TokenUsage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CachedTokens:     usage.CachedTokens,
+		Cost:             cost,
+		Priced:           priced,
+	}
+}
+
+// addStreamError records a failed reply, keeping the details of provider
+// errors so they render distinctly.
+func (m *MainView) addStreamError(err error) {
+	var perr *api.ProviderError
+	if !errors.As(err, &perr) {
+		m.session.AddErrorMessage(err.Error())
+		return
+	}
+
+	m.session.AddProviderError(perr.Message, types.ErrorDetail{
+		Provider:   perr.Provider,
+		Kind:       string(perr.Kind),
+		StatusCode: perr.StatusCode,
+		Type:       perr.Type,
+		RequestID:  perr.RequestID,
+		RetryAfter: perr.RetryAfter,
+		Hint:       m.errorHint(perr.Kind),
+	})
+}
+
+// errorHint suggests a fix for a kind of provider error from the target
+// that answered last.
+func (m *MainView) errorHint(kind api.ErrorKind) string {
+	providerID, _ := api.SplitTarget(m.streamTarget)
+	switch kind {
+	case api.ErrorAuth:
+		if keyEnv := api.KeyEnvVars()[providerID]; keyEnv != "" {
+			return "Check that " + keyEnv + " holds a valid API key."
+		}
+		return "Check the provider's credentials."
+	case api.ErrorQuota:
+		return "The account is out of credit or quota. Top it up or press Tab to switch providers."
+	case api.ErrorRateLimit:
+		return "Too many requests. Wait a moment before sending again."
+	case api.ErrorModelNotFound:
+		return "The model " + api.DefaultModel(m.streamTarget) + " is not available. Use /model to pick another one."
+	case api.ErrorNetwork:
+		return "Check your connection or whether the server is running."
+	case api.ErrorServer:
+		return "The provider is having trouble. Try again shortly or switch providers."
+	}
+	return ""
+}
+
+// stopStreaming cancels the in-flight request, keeps the partial reply as
+// interrupted and unlocks the input.
+func (m *MainView) stopStreaming() {
+	m.session.InterruptResponse()
+	m.session.InterruptToolCalls()
+	m.restoreSteering()
+	m.endStream()
+}
+
+func (m *MainView) endStream() {
+	if m.cancelStream != nil {
+		m.cancelStream()
+		m.cancelStream = nil
+	}
+	m.streaming = false
+	m.streamStatus = ""
+	m.pendingTools = nil
+	m.saveSession()
+}
@@ -0,0 +1,107 @@
+package views
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"Chat2/internal/api"
+	"Chat2/internal/schema"
+	"Chat2/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxSchemaRetries caps how often a reply that does not match the schema
+// is sent back to the model for correction.
+const maxSchemaRetries = 2
+
+// SetJSONSchema loads the schema file at path and makes every following
+// reply a JSON value matching it.
+func (m *MainView) SetJSONSchema(path string) {
+	s, err := schema.Load(path)
+	if err != nil {
+		m.session.AddErrorMessage("Cannot use schema: " + err.Error())
+		return
+	}
+	m.jsonSchema = s
+	m.session.AddMessage(types.NewSuccess("🧾 JSON mode on: replies must match the " + s.Name + " schema. /json off to leave."))
+}
+
+func (m *MainView) ClearJSONSchema() {
+	if m.jsonSchema == nil {
+		m.session.AddNotice("JSON mode is not on.")
+		return
+	}
+	m.jsonSchema = nil
+	m.session.AddMessage(types.NewSuccess("💬 JSON mode off."))
+}
+
+// responseFormat asks the provider for JSON matching the active schema.
+func (m *MainView) responseFormat() *api.ResponseFormat {
+	if m.jsonSchema == nil {
+		return nil
+	}
+	return &api.ResponseFormat{Name: m.jsonSchema.Name, Schema: m.jsonSchema.Raw}
+}
+
+// formatStructuredReply validates the finished reply against the active
+// schema and, if it matches, replaces it with the pretty-printed JSON.
+func (m *MainView) formatStructuredReply() error {
+	reply, err := m.checkStructuredReply(m.session.StreamingResponse())
+	if err != nil {
+		return err
+	}
+	m.session.SetResponseContent(reply)
+	m.session.SetResponseSchema(m.jsonSchema.Name)
+	return nil
+}
+
+// checkStructuredReply validates reply against the active schema and
+// returns it pretty-printed.
+func (m *MainView) checkStructuredReply(reply string) (string, error) {
+	reply = extractJSON(reply)
+	if reply == "" {
+		return "", &schema.ValidationError{Problems: []string{"the reply is empty"}}
+	}
+	if err := m.jsonSchema.ValidateJSON([]byte(reply)); err != nil {
+		return "", err
+	}
+
+	var pretty bytes.Buffer
+	if err := json.Indent(&pretty, []byte(reply), "", "  "); err == nil {
+		reply = pretty.String()
+	}
+	return reply, nil
+}
+
+// rejectStructuredReply asks the model to correct a reply that does not
+// match the schema, until maxSchemaRetries is reached.
+func (m *MainView) rejectStructuredReply(err error) tea.Cmd {
+	m.session.FinishResponse()
+	if m.schemaRetries >= maxSchemaRetries {
+		m.session.AddErrorMessage(fmt.Sprintf("The reply still does not match the %s schema after %d retries: %v", m.jsonSchema.Name, maxSchemaRetries, err))
+		m.endStream()
+		return nil
+	}
+
+	m.schemaRetries++
+	m.session.AddUserMessage(fmt.Sprintf("Your reply does not match the JSON schema: %v\nReply again with only the corrected JSON.", err))
+	m.streamStatus = fmt.Sprintf("🧾 Fixing JSON (%d/%d)…", m.schemaRetries, maxSchemaRetries)
+	return m.requestReply()
+}
+
+// extractJSON trims the reply and strips a Markdown code fence around it,
+// which models often add despite being told not to.
+func extractJSON(reply string) string {
+	reply = strings.TrimSpace(reply)
+	if !strings.HasPrefix(reply, "```") {
+		return reply
+	}
+	reply = strings.TrimPrefix(reply, "```")
+	if newline := strings.IndexByte(reply, '\n'); newline >= 0 {
+		reply = reply[newline+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(reply), "```"))
+}
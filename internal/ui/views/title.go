@@ -0,0 +1,157 @@
+package views
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"Chat2/internal/api"
+	"Chat2/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// titlePrompt asks for a session title.
+	titlePrompt = "Name this conversation with a title of at most six words. Reply with only the title, without quotes or a trailing period."
+	// maxTitleRunes caps a generated title and the conversation excerpt it
+	// is generated from.
+	maxTitleRunes   = 60
+	maxExcerptRunes = 2000
+	titleTimeout    = 30 * time.Second
+	// titleMaxTokens leaves reasoning models room to think before the few
+	// tokens of the title.
+	titleMaxTokens = 1024
+)
+
+// titleMsg delivers the title generated for the session with the given ID.
+type titleMsg struct {
+	sessionID string
+	title     string
+	usage     *types.TokenUsage
+}
+
+// requestTitle asks a model in the background to name the session once it
+// has its first reply. The configured title model is used, or else the
+// current one. If it fails or sends no usable title, the start of the first
+// prompt names the session.
+func (m *MainView) requestTitle() tea.Cmd {
+	if m.session.Title != "" || m.titleRequested == m.session.ID {
+		return nil
+	}
+	var prompt, reply string
+	for _, msg := range m.session.Messages {
+		switch {
+		case msg.Role == types.RoleUser && prompt == "":
+			prompt = msg.Content
+		case msg.Role == types.RoleAssistant && msg.Content != "" && prompt != "":
+			reply = msg.Content
+		}
+		if reply != "" {
+			break
+		}
+	}
+	if reply == "" {
+		return nil
+	}
+	m.titleRequested = m.session.ID
+
+	target := m.titleModel
+	if target == "" {
+		target = m.currentTarget()
+	}
+	req := api.ChatRequest{
+		Messages: []api.ChatMessage{
+			{Role: api.RoleSystem, Content: titlePrompt},
+			{Role: api.RoleUser, Content: "User: " + excerpt(prompt) + "\n\nAssistant: " + excerpt(reply)},
+		},
+		Params: api.Params{MaxTokens: titleMaxTokens},
+	}
+	sessionID, apiKeys := m.session.ID, m.apiKeys
+
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), titleTimeout)
+		defer cancel()
+
+		msg := titleMsg{sessionID: sessionID}
+		var title strings.Builder
+		for event := range api.StreamTarget(ctx, target, req, apiKeys) {
+			switch event.Type {
+			case api.EventDelta:
+				title.WriteString(event.Text)
+			case api.EventUsage:
+				usage := priceUsage(target, *event.Usage)
+				msg.usage = &usage
+			case api.EventError:
+				title.Reset()
+			}
+		}
+		msg.title = cleanTitle(title.String())
+		if msg.title == "" {
+			msg.title = promptTitle(prompt)
+		}
+		return msg
+	}
+}
+
+// handleTitle names the session the title was generated for, unless the
+// user named it in the meantime or another session is open by now.
+func (m *MainView) handleTitle(msg titleMsg) {
+	if msg.sessionID != m.session.ID {
+		return
+	}
+	if msg.usage != nil {
+		m.session.Usage.Add(*msg.usage)
+	}
+	if msg.title == "" || m.session.Title != "" {
+		return
+	}
+	m.session.Title = msg.title
+	m.saveSession()
+}
+
+func (m *MainView) SessionTitle() string {
+	return m.session.Title
+}
+
+// SetSessionTitle names the current session, replacing a generated title.
+func (m *MainView) SetSessionTitle(title string) {
+	title = strings.TrimSpace(title)
+	if runes := []rune(title); len(runes) > maxTitleRunes {
+		title = string(runes[:maxTitleRunes])
+	}
+	m.session.Title = title
+	m.titleRequested = m.session.ID
+	m.saveSession()
+}
+
+// cleanTitle keeps the first line of a generated title without the quotes,
+// label or trailing period models tend to add.
+func cleanTitle(title string) string {
+	title, _, _ = strings.Cut(strings.TrimSpace(title), "\n")
+	title = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(title, "Title:"), "title:"))
+	title = strings.Trim(title, "\"'“”*# ")
+	title = strings.TrimSuffix(title, ".")
+	if runes := []rune(title); len(runes) > maxTitleRunes {
+		title = string(runes[:maxTitleRunes-1]) + "…"
+	}
+	return title
+}
+
+// promptTitle names a session after the first line of its first prompt.
+func promptTitle(prompt string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	title := strings.Join(strings.Fields(line), " ")
+	if runes := []rune(title); len(runes) > maxTitleRunes {
+		title = string(runes[:maxTitleRunes-1]) + "…"
+	}
+	return title
+}
+
+// excerpt shortens text to maxExcerptRunes.
+func excerpt(text string) string {
+	if runes := []rune(text); len(runes) > maxExcerptRunes {
+		return string(runes[:maxExcerptRunes]) + "…"
+	}
+	return text
+}
@@ -0,0 +1,210 @@
+package views
+
+import (
+	"context"
+	"strings"
+
+	"Chat2/internal/api"
+	"Chat2/internal/tools"
+	"Chat2/internal/types"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxToolRounds caps how often a reply may call tools before the turn is
+// ended, so a model stuck in a loop cannot run forever.
+const maxToolRounds = 10
+
+// toolOutputBuffer lets a tool run ahead of the UI while streaming output.
+const toolOutputBuffer = 64
+
+// toolResultMsg delivers the outcome of a tool call. id ties it to the
+// stream that requested the call.
+type toolResultMsg struct {
+	id     int
+	callID string
+	output string
+	err    error
+}
+
+// toolOutputMsg delivers output a tool produced while still running.
+type toolOutputMsg struct {
+	id     int
+	callID string
+	chunk  string
+	output <-chan string
+}
+
+// runTool runs call in the background. Output the tool streams while it
+// runs is delivered as toolOutputMsg until the toolResultMsg arrives.
+func runTool(ctx context.Context, id int, registry *tools.Registry, call types.ToolCall) tea.Cmd {
+	output := make(chan string, toolOutputBuffer)
+	toolCtx := tools.WithOutput(ctx, func(chunk string) {
+		select {
+		case output <- chunk:
+		case <-ctx.Done():
+		}
+	})
+
+	run := func() tea.Msg {
+		result, err := registry.Run(toolCtx, api.ToolCall(call))
+		close(output)
+		return toolResultMsg{id: id, callID: call.ID, output: result, err: err}
+	}
+	return tea.Batch(run, waitForToolOutput(id, call.ID, output))
+}
+
+func waitForToolOutput(id int, callID string, output <-chan string) tea.Cmd {
+	return func() tea.Msg {
+		chunk, ok := <-output
+		if !ok {
+			return nil
+		}
+		return toolOutputMsg{id: id, callID: callID, chunk: chunk, output: output}
+	}
+}
+
+// runNextTool starts the next pending tool call of the reply. Once all have
+// run, the results are sent back to the model for the next round.
+func (m *MainView) runNextTool() tea.Cmd {
+	if len(m.pendingTools) == 0 {
+		m.toolRounds++
+		if reason := m.stepLimit(); reason != "" {
+			if m.agentMode {
+				m.session.AddNotice(reason)
+			} else {
+				m.session.AddErrorMessage(reason)
+			}
+			m.endStream()
+			return nil
+		}
+		m.streamStatus = ""
+		return m.requestReply()
+	}
+
+	call := m.pendingTools[0]
+	m.pendingTools = m.pendingTools[1:]
+	m.session.BeginToolCall(call)
+
+	if m.agentMode && call.Name == planToolName {
+		result, err := m.applyPlan(call.Arguments)
+		if err != nil {
+			m.session.FinishToolCall(call.ID, "Error: "+err.Error(), true)
+		} else {
+			m.session.FinishToolCall(call.ID, result, false)
+		}
+		return m.runNextTool()
+	}
+
+	switch m.tools.Permission(api.ToolCall(call)) {
+	case tools.PermissionDeny:
+		m.session.FinishToolCall(call.ID, "Error: this call is not allowed by the configuration.", true)
+		return m.runNextTool()
+	case tools.PermissionAsk:
+		if !m.approvedTools[call.Name] {
+			m.askApproval(call)
+			return nil
+		}
+	}
+	return m.startTool(call)
+}
+
+func (m *MainView) startTool(call types.ToolCall) tea.Cmd {
+	m.streamStatus = "🔧 Running " + call.Name + "…"
+	return runTool(m.streamCtx, m.streamID, m.tools, call)
+}
+
+// Options of the tool approval dialog
+const (
+	approveOnce = iota
+	approveAlways
+	approveDeny
+)
+
+// askApproval shows the approval dialog for call, which waits until the user
+// answers.
+func (m *MainView) askApproval(call types.ToolCall) {
+	m.pendingApproval = &call
+	m.approvalSelected = approveOnce
+	m.previousState = m.state
+	m.state = types.StateToolConfirm
+	m.streamStatus = "✋ Waiting for approval of " + call.Name
+}
+
+func (m *MainView) handleToolConfirmKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyLeft, tea.KeyShiftTab:
+		m.approvalSelected = (m.approvalSelected + 2) % 3
+		if !m.canApproveAlways() && m.approvalSelected == approveAlways {
+			m.approvalSelected = approveOnce
+		}
+	case tea.KeyRight, tea.KeyTab:
+		m.approvalSelected = (m.approvalSelected + 1) % 3
+		if !m.canApproveAlways() && m.approvalSelected == approveAlways {
+			m.approvalSelected = approveDeny
+		}
+	case tea.KeyEnter:
+		return m, m.resolveApproval(m.approvalSelected)
+	case tea.KeyEsc:
+		return m, m.resolveApproval(approveDeny)
+	case tea.KeyRunes:
+		switch strings.ToLower(string(msg.Runes)) {
+		case "y":
+			return m, m.resolveApproval(approveOnce)
+		case "a":
+			if m.canApproveAlways() {
+				return m, m.resolveApproval(approveAlways)
+			}
+		case "n":
+			return m, m.resolveApproval(approveDeny)
+		}
+	}
+	return m, nil
+}
+
+// resolveApproval applies the user's answer to the pending tool call.
+func (m *MainView) resolveApproval(choice int) tea.Cmd {
+	call := m.pendingApproval
+	m.pendingApproval = nil
+	m.state = m.previousState
+	if call == nil || !m.streaming {
+		return nil
+	}
+
+	switch choice {
+	case approveAlways:
+		m.approvedTools[call.Name] = true
+		m.session.AddNotice("🔓 " + call.Name + " is allowed for the rest of this session")
+	case approveDeny:
+		m.session.FinishToolCall(call.ID, "The user denied this call.", true)
+		return m.runNextTool()
+	}
+	return m.startTool(*call)
+}
+
+// canApproveAlways reports whether the pending call's tool may be approved
+// for the rest of the session. Commands are approved one at a time.
+func (m *MainView) canApproveAlways() bool {
+	return m.pendingApproval != nil && m.tools.CanApproveAlways(m.pendingApproval.Name)
+}
+
+func (m *MainView) handleToolOutput(msg toolOutputMsg) tea.Cmd {
+	if msg.id != m.streamID || !m.streaming {
+		return nil
+	}
+	m.session.AppendToolOutput(msg.callID, msg.chunk)
+	return waitForToolOutput(msg.id, msg.callID, msg.output)
+}
+
+func (m *MainView) handleToolResult(msg toolResultMsg) tea.Cmd {
+	if msg.id != m.streamID || !m.streaming {
+		return nil
+	}
+
+	if msg.err != nil {
+		m.session.FinishToolCall(msg.callID, "Error: "+msg.err.Error(), true)
+	} else {
+		m.session.FinishToolCall(msg.callID, msg.output, false)
+	}
+	return m.runNextTool()
+}
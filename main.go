@@ -1,16 +1,20 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 
 	"Chat2/internal/app"
 )
 
 func main() {
+	continueSession := flag.Bool("continue", false, "reopen the most recent session")
+	flag.Parse()
+
 	fmt.Printf("Starting PUKU CLI...\n")
 	
 	// Initialize app
-	application := app.New()
+	application := app.New(app.Options{Continue: *continueSession})
 	fmt.Printf("App initialized successfully\n")
 	
 	fmt.Printf("Starting TUI program...\n")